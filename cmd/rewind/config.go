@@ -0,0 +1,73 @@
+package rewind
+
+import (
+	"github.com/brightercommand/Rewind/internal/config"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+)
+
+// configPath the rewind.yaml to load, if empty we look for one in the root of the source folder
+var configPath string
+
+// overrides the configuration flags, which win over anything in rewind.yaml
+var overrides = config.Default()
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&configPath, "config", "", "the "+config.FileName+" to read the layout from (default: "+config.FileName+" in the source folder)")
+	flags.StringVar(&overrides.SharedFolder, "shared-folder", overrides.SharedFolder, "the folder of docs shared by every version")
+	flags.StringVar(&overrides.SummaryFolder, "summary-folder", overrides.SummaryFolder, "a folder in the source that is not a version")
+	flags.StringVar(&overrides.RootFolder, "root-folder", overrides.RootFolder, "the folder of files copied as-is to the root of the book")
+	flags.StringVar(&overrides.TocFile, "toc-file", overrides.TocFile, "the name of the table of contents file")
+	flags.StringVar(&overrides.GitBookFile, "gitbook-file", overrides.GitBookFile, "the name of the GitBook configuration file")
+	flags.StringVar(&overrides.VarsFile, "vars-file", overrides.VarsFile, "the name of the file of template variables")
+	flags.StringVar(&overrides.StaticFolder, "static-folder", overrides.StaticFolder, "the folder that holds static assets")
+	flags.StringVar(&overrides.ImageFolder, "image-folder", overrides.ImageFolder, "the folder, within the static folder, that holds images")
+	flags.StringVar(&overrides.PartialsFolder, "partials-folder", overrides.PartialsFolder, "the folder that holds the partials that docs include")
+	flags.StringVar(&overrides.SamplesFolder, "samples-folder", overrides.SamplesFolder, "the folder that holds the source files that docs take snippets from")
+	flags.StringSliceVar(&overrides.ImageExtensions, "image-extensions", overrides.ImageExtensions, "the extensions of the files treated as images")
+	flags.StringVar(&overrides.VersionPattern, "version-pattern", overrides.VersionPattern, "a regular expression that a version folder's name must match")
+	flags.StringVar(&overrides.Output.ContentFolder, "content-folder", overrides.Output.ContentFolder, "the folder, in the root of the book, that holds every version")
+	flags.StringVar(&overrides.Output.VersionFolder, "version-folder", overrides.Output.VersionFolder, "the name of each version's folder, "+config.VersionPlaceholder+" is the name of the version")
+}
+
+// loadConfig loads the rewind.yaml for a source folder, and applies any configuration flags that were set over it
+func loadConfig(cmd *cobra.Command, sourcePath string) (*config.Config, error) {
+	//a rewind.yaml in the source folder is optional, but one named by --config must exist
+	path := configPath
+	if path == "" {
+		path = sourcePath + "/" + config.FileName
+	} else if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	log.Print("Loading configuration from " + path + "...")
+	c, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := cmd.Flags()
+	set := func(name string, apply func()) {
+		if flags.Changed(name) {
+			apply()
+		}
+	}
+	set("shared-folder", func() { c.SharedFolder = overrides.SharedFolder })
+	set("summary-folder", func() { c.SummaryFolder = overrides.SummaryFolder })
+	set("root-folder", func() { c.RootFolder = overrides.RootFolder })
+	set("toc-file", func() { c.TocFile = overrides.TocFile })
+	set("gitbook-file", func() { c.GitBookFile = overrides.GitBookFile })
+	set("vars-file", func() { c.VarsFile = overrides.VarsFile })
+	set("static-folder", func() { c.StaticFolder = overrides.StaticFolder })
+	set("image-folder", func() { c.ImageFolder = overrides.ImageFolder })
+	set("partials-folder", func() { c.PartialsFolder = overrides.PartialsFolder })
+	set("samples-folder", func() { c.SamplesFolder = overrides.SamplesFolder })
+	set("image-extensions", func() { c.ImageExtensions = overrides.ImageExtensions })
+	set("version-pattern", func() { c.VersionPattern = overrides.VersionPattern })
+	set("content-folder", func() { c.Output.ContentFolder = overrides.Output.ContentFolder })
+	set("version-folder", func() { c.Output.VersionFolder = overrides.Output.VersionFolder })
+
+	return c, c.Validate()
+}
@@ -1,12 +1,22 @@
 package rewind
 
 import (
+	"context"
 	"github.com/brightercommand/Rewind/internal/book"
+	"github.com/brightercommand/Rewind/internal/pages"
 	"github.com/brightercommand/Rewind/internal/sources"
 	"github.com/spf13/cobra"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
+var versionOrderName string
+var dryRun bool
+var force bool
+var noCache bool
+
 var makeBookCmd = &cobra.Command{
 	Use:     "makebook",
 	Aliases: []string{"make"},
@@ -14,6 +24,7 @@ var makeBookCmd = &cobra.Command{
 	Long: `Turns versioned markdown files into a gitbook.
 			Expects a source path with a pointer to a director with the following structure:
 			- source
+                - root //files copied as-is to the root of the book
                 - shared //common docs
 				    - .toc.yaml // table of contents
                     - doc.md // one or more markdown files
@@ -29,30 +40,80 @@ var makeBookCmd = &cobra.Command{
 
 		log.Print("Creating book...")
 		log.Print("Finding sources...")
-		sources, err := findSources(args[0])
+		sources, err := findSources(cmd, args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		versionOrder, err := pages.ParseVersionOrder(versionOrderName)
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		//Ctrl-C cancels the build, and we clean up before we exit
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
 		log.Print("Making book...")
-		book, err := book.MakeBook(sources, args[1])
+		book, err := book.MakeBook(ctx, sources, args[1], book.Options{VersionOrder: versionOrder, Force: force, NoCache: noCache, Jobs: jobs})
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		if dryRun {
+			log.Print("Planning book...")
+			err = plan(cmd, book)
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
 		log.Print("Publishing book...")
-		err = book.Publish()
+		err = book.Publish(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
 	},
 }
 
-func findSources(sourcePath string) (*sources.Sources, error) {
+func init() {
+	addPublishFlags(makeBookCmd)
+	makeBookCmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"print what would be copied, overridden and overwritten, and the changes to SUMMARY.md, without writing the book")
+}
+
+// addPublishFlags adds the flags that control how we make and publish a book to a command
+func addPublishFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&versionOrderName, "version-order", "ascending",
+		"list versions in SUMMARY.md in ascending (oldest first) or descending (newest first) order")
+	cmd.Flags().BoolVar(&force, "force", false,
+		"publish even if the destination holds files that rewind did not write")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false,
+		"write every file, rather than skipping those that have not changed since the last publish")
+}
+
+// plan prints what publishing the book would do
+func plan(cmd *cobra.Command, b *book.Book) error {
+	p, err := b.Plan()
+	if err != nil {
+		return err
+	}
+	return p.Write(cmd.OutOrStdout())
+}
+
+func findSources(cmd *cobra.Command, sourcePath string) (*sources.Sources, error) {
+	cfg, err := loadConfig(cmd, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Print("Finding sources in " + sourcePath + "...")
 
 	src := sources.NewSources()
-	err := src.FindFromPath(sourcePath)
+	src.Config = cfg
+	src.Jobs = jobs
+	err = src.FindFromPath(sourcePath)
 	if err != nil {
 		return nil, err
 	}
@@ -16,8 +16,15 @@ var rootCmd = &cobra.Command{
 			A toc file in shared and version is used to supply information on how to build the toc.`,
 }
 
+// jobs how many files or folders we work on at once
+var jobs int
+
 func init() {
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "how many files or folders to work on at once (default: one per CPU)")
 	rootCmd.AddCommand(makeBookCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(serveCmd)
 }
 
 func Execute() {
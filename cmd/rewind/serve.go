@@ -0,0 +1,109 @@
+package rewind
+
+import (
+	"context"
+	"errors"
+	"github.com/brightercommand/Rewind/internal/book"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/serve"
+	"github.com/brightercommand/Rewind/internal/watch"
+	"github.com/spf13/cobra"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+var serveAddress string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Previews the book in a browser, and reloads it whenever the sources change",
+	Long: `Makes the book in memory, without publishing it, and serves it over HTTP so that you can preview it.
+			Each doc is rendered as a page, with the table of contents of its version to navigate by, and a switcher
+			to move between versions. We watch the sources, as watch does, and make the book again whenever they
+			change; open pages reload themselves. A failed build is shown on the page. Ctrl-C stops the server.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sourcePath := args[0]
+
+		versionOrder, err := pages.ParseVersionOrder(versionOrderName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts := book.Options{VersionOrder: versionOrder, Jobs: jobs}
+
+		//Ctrl-C stops the server
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		watcher, err := watch.New(sourcePath, watchInterval, watchDebounce)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		server := serve.New()
+		build(ctx, cmd, sourcePath, opts, server)
+
+		httpServer := &http.Server{Addr: serveAddress, Handler: server}
+		go func() {
+			<-ctx.Done()
+			_ = httpServer.Close()
+		}()
+
+		go func() {
+			for {
+				changed, err := watcher.Next(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					server.SetError(err)
+					continue
+				}
+
+				log.Print("Changed: " + strings.Join(changed, ", "))
+				build(ctx, cmd, sourcePath, opts, server)
+			}
+		}()
+
+		log.Print("Serving " + sourcePath + " at http://" + serveAddress + "/ ...")
+		err = httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+		log.Print("Stopped serving")
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddress, "address", "localhost:4000", "the address to serve the book at")
+	serveCmd.Flags().StringVar(&versionOrderName, "version-order", "ascending",
+		"list versions in ascending (oldest first) or descending (newest first) order")
+	serveCmd.Flags().DurationVar(&watchInterval, "interval", defaultWatchInterval,
+		"how often to look for changes to the sources")
+	serveCmd.Flags().DurationVar(&watchDebounce, "debounce", defaultWatchDebounce,
+		"how long the sources must stop changing before we rebuild")
+}
+
+// build makes the book in memory, and gives it to the server, or tells the server why it failed
+func build(ctx context.Context, cmd *cobra.Command, sourcePath string, opts book.Options, server *serve.Server) {
+	sources, err := findSources(cmd, sourcePath)
+	if err != nil {
+		log.Print("Build failed: " + err.Error())
+		server.SetError(err)
+		return
+	}
+
+	b, err := book.MakeBook(ctx, sources, "", opts)
+	if err != nil {
+		log.Print("Build failed: " + err.Error())
+		server.SetError(err)
+		return
+	}
+
+	log.Print("Built book")
+	server.SetBook(b)
+}
@@ -0,0 +1,43 @@
+package rewind
+
+import (
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/book"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks versioned markdown files for problems, without making a book",
+	Long: `Checks versioned markdown files for problems, without making a book.
+			Expects a source path with the same structure as makebook. Merges the docs and table of contents
+			for each version, and reports every problem found:
+			- a missing .toc.yaml, or one that cannot be parsed
+			- a toc entry that points to a file that is not a doc in that version
+			- a doc that no toc entry points to
+			- an entry name or file used more than once in a version
+			Exits with a non-zero exit code if there are any problems.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		log.Print("Validating sources...")
+		sources, err := findSources(cmd, args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		problems := book.Validate(sources)
+		for _, problem := range problems {
+			fmt.Fprintln(cmd.OutOrStdout(), problem.Error())
+		}
+
+		if len(problems) > 0 {
+			log.Printf("Found %d problems", len(problems))
+			os.Exit(1)
+		}
+
+		log.Print("No problems found")
+	},
+}
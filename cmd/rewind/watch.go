@@ -0,0 +1,143 @@
+package rewind
+
+import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/book"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"github.com/brightercommand/Rewind/internal/watch"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultWatchInterval how often we look for changes to the sources, unless told otherwise
+const defaultWatchInterval = 500 * time.Millisecond
+
+// defaultWatchDebounce how long the sources must stop changing before we rebuild, unless told otherwise
+const defaultWatchDebounce = time.Second
+
+var watchInterval time.Duration
+var watchDebounce time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Makes the book, then makes it again whenever the sources change",
+	Long: `Makes the book, as makebook does, then watches the sources and makes it again whenever they change.
+			A change to a shared doc rebuilds every version, a change to a version's doc rebuilds that version, and
+			the versions that inherit from it, and a change to a TOC file also regenerates SUMMARY.md. Adding,
+			renaming or deleting a doc or image rebuilds every version, as their links may point to it.
+			We wait until the sources have stopped changing, so a burst of edits is one rebuild.
+			A failed build is reported, and we carry on watching. Ctrl-C stops watching.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		sourcePath, destPath := args[0], args[1]
+
+		versionOrder, err := pages.ParseVersionOrder(versionOrderName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts := book.Options{VersionOrder: versionOrder, Force: force, NoCache: noCache, Jobs: jobs}
+
+		//Ctrl-C stops watching, and cancels any build in progress
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		//we start watching before the first build, so we do not miss an edit made during it
+		watcher, err := watch.New(sourcePath, watchInterval, watchDebounce)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		published := rebuild(ctx, cmd, sourcePath, destPath, opts, nil, nil)
+
+		for {
+			log.Print("Watching " + sourcePath + " for changes...")
+			changed, err := watcher.Next(ctx)
+			if ctx.Err() != nil {
+				log.Print("Stopped watching")
+				return
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			log.Print("Changed: " + strings.Join(changed, ", "))
+			published = rebuild(ctx, cmd, sourcePath, destPath, opts, published, changed)
+		}
+	},
+}
+
+func init() {
+	addPublishFlags(watchCmd)
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", defaultWatchInterval,
+		"how often to look for changes to the sources")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", defaultWatchDebounce,
+		"how long the sources must stop changing before we rebuild")
+}
+
+// rebuild makes the book and publishes the parts of it affected by the changed files, or all of it if changed is nil.
+// Published are the sources we last published, which tell us if a doc or image has been added or removed since.
+// We log, rather than return, a failure, so that we carry on watching.
+// It returns the sources it published, or published if it failed.
+func rebuild(ctx context.Context, cmd *cobra.Command, sourcePath string, destPath string, opts book.Options, published *sources.Sources, changed []string) *sources.Sources {
+	src, err := findSources(cmd, sourcePath)
+	if err != nil {
+		log.Print("Build failed: " + err.Error())
+		return published
+	}
+
+	changes := book.AllChanges
+	if changed != nil {
+		changes = book.ChangesFor(published, src, changed)
+	}
+	log.Print("Rebuilding " + describeChanges(changes) + "...")
+
+	b, err := book.MakeBook(ctx, src, destPath, opts)
+	if err != nil {
+		log.Print("Build failed: " + err.Error())
+		return published
+	}
+
+	err = b.PublishChanges(ctx, changes)
+	if err != nil {
+		log.Print("Build failed: " + err.Error())
+		return published
+	}
+	log.Print("Published book to " + destPath)
+	return src
+}
+
+// describeChanges describes what a rebuild writes, for the log
+func describeChanges(changes book.Changes) string {
+	if changes.All {
+		return "the whole book"
+	}
+
+	var parts []string
+	if changes.Summary {
+		parts = append(parts, pages.SummaryFileName)
+	}
+	if changes.Root {
+		parts = append(parts, "the root files")
+	}
+
+	var versions []string
+	for version := range changes.Versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	if len(versions) > 0 {
+		parts = append(parts, "versions "+strings.Join(versions, ", "))
+	}
+
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, " and ")
+}
@@ -1,116 +1,301 @@
 package book
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/config"
 	"github.com/brightercommand/Rewind/internal/pages"
 	"github.com/brightercommand/Rewind/internal/sources"
 	"gopkg.in/yaml.v3"
 	"io"
 	"log"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// Book The book we publish, with the layout it was read from and is published to in Config
+// Overrides lists the docs and images that a version takes from a layer of its chain in place of shared, or a base.
+// Tocs is the table of contents of each version, in the order that SUMMARY.md lists them.
 type Book struct {
-	Root     *pages.Root
-	Versions map[string]pages.Version
+	Root      *pages.Root
+	Versions  map[string]pages.Version
+	Options   Options
+	Config    *config.Config
+	Overrides []Override
+	Tocs      []pages.OrderedVersionTocs
+
+	tocs map[string]parsedToc
 }
 
-func MakeBook(s *sources.Sources, destPath string) (*Book, error) {
+// Options How to make and publish a book. The zero value gives the defaults.
+type Options struct {
+	// VersionOrder whether SUMMARY.md lists the versions oldest or newest first
+	VersionOrder pages.VersionOrder
+	// Force publish even if the destination holds files that Rewind did not write
+	Force bool
+	// NoCache write every file, rather than skipping those that have not changed since the last publish, and do not
+	// keep a build cache
+	NoCache bool
+	// CacheDir the folder that holds the build cache; if empty we use a folder in the user's cache folder
+	CacheDir string
+	// Jobs how many TOC files we parse, or files we write, at once; if less than one we use one per CPU
+	Jobs int
+}
+
+// MakeBook merges the sources into a book that can be published to destPath.
+// It stops, and returns the context's error, if the context is cancelled.
+func MakeBook(ctx context.Context, s *sources.Sources, destPath string, opts Options) (*Book, error) {
 	b := &Book{
+		Options: opts,
+		Config:  sourceConfig(s),
 		Root: &pages.Root{
 			DestPath:   destPath,
 			SourcePath: s.Root.SourcePath,
 			GitBook:    s.Root.GitBook,
+			ReadMe:     s.Root.ReadMe,
+			Files:      s.Root.Files,
 		},
 		Versions: make(map[string]pages.Version),
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	//we make the TOC even if a version fails, so that one run reports every error
+	var errs Errors
 	log.Print("Making versions...")
-	b.MakeVersions(s)
+	errs.Add(b.MakeVersions(s))
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	log.Print("Making TOC...")
-	err := b.MakeTOC(s)
-	if err != nil {
+	b.parseTocs(ctx, s)
+	errs.Add(b.MakeTOC(s))
+
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
 	return b, nil
 }
 
-func (b *Book) Publish() error {
+// Publish writes the book to its destination.
+// We build the whole book in a staging folder next to the destination, and only move the files into the destination
+// once every one has been written, so a failure, or cancelling the context, leaves the destination as it was. Once we
+// start to move files into place we no longer stop if the context is cancelled, so we never leave a half-updated book.
+// The staging folder is always removed.
+// Before we write anything we check that the destination is safe to write to; see checkDestination.
+func (b *Book) Publish(ctx context.Context) error {
+	return b.PublishChanges(ctx, AllChanges)
+}
+
+// PublishChanges writes the book to its destination, as Publish does, but only looks again at the files that the
+// changes affect; we keep what we published last time for every other file. See ChangesFor.
+func (b *Book) PublishChanges(ctx context.Context, changes Changes) (err error) {
 
-	//create root directory
 	rootPath := b.Root.DestPath
-	if _, err := os.Stat(rootPath); os.IsNotExist(err) {
-		err := os.MkdirAll(rootPath, os.ModePerm)
+
+	err = b.checkDestination()
+	if err != nil {
+		return err
+	}
+
+	//the manifest of what we wrote last time tells us what we can remove
+	old, err := readManifest(rootPath)
+	if err != nil {
+		return err
+	}
+
+	//the build cache tells us what we can skip
+	cache := newBuildCache()
+	if !b.Options.NoCache {
+		cache, err = loadCache(b.Options.CacheDir, rootPath)
 		if err != nil {
 			return err
 		}
 	}
 
-	//copy root files
-	err := copyFile(b.Root.WorkDir, b.Root.DestPath, pages.SummaryFileName)
+	staging, err := newStaging(rootPath)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if c := staging.remove(); c != nil && err == nil {
+			err = c
+		}
+	}()
 
-	err = copyFile(b.Root.GitBook.SourcePath, b.Root.DestPath, b.Root.GitBook.Storage.Name())
+	//copy the root files, then the versioned files
+	manifest, staged, err := b.stage(ctx, staging.path, cache, old, changes)
 	if err != nil {
 		return err
 	}
 
-	//copy versioned files
-	for _, version := range b.Versions {
+	//last chance to stop before we change the destination
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		destPath := version.DestPath
-		if _, err := os.Stat(destPath); os.IsNotExist(err) {
-			err := os.MkdirAll(destPath, os.ModePerm)
-			if err != nil {
-				return err
-			}
+	log.Print("Moving book into " + rootPath + "...")
+	err = staging.swap(rootPath, old, manifest, staged, func() error { return b.prune(old) })
+	if err != nil {
+		return err
+	}
+
+	return b.updateCache(cache, manifest, staged)
+}
+
+// updateCache records what we wrote to the destination in the build cache.
+// The book has been published by now, so we only log a failure to save the cache; the next publish just does more work.
+func (b *Book) updateCache(cache *buildCache, manifest *Manifest, staged map[string]bool) error {
+	for _, entry := range manifest.Files {
+		if !staged[entry.Path] {
+			continue
 		}
 
-		for _, doc := range version.Docs {
-			err = copyFile(doc.SourcePath, destPath, doc.Storage.Name())
-			if err != nil {
-				return err
-			}
+		err := cache.written(b.Root.DestPath, entry.Path, entry.Hash)
+		if err != nil {
+			return err
 		}
 	}
+	cache.prune(manifest)
 
-	//clean up the temporary files
-	err = b.ClearWorkDir()
+	err := cache.save()
 	if err != nil {
-		return err
+		log.Print("Could not save the build cache: " + err.Error())
 	}
-
 	return nil
 }
 
-func (b *Book) MakeVersions(s *sources.Sources) {
-	for key, version := range s.Versions {
+// MakeVersions merges the shared docs and images with those of each version.
+// Anything a version supplies overwrites a shared doc or image with the same name.
+// It returns an error if two images would be published to paths that differ only by case.
+// The errors from every version are returned together, as Errors.
+func (b *Book) MakeVersions(s *sources.Sources) error {
+	var errs Errors
+	for _, key := range sortedKeys(s.Versions) {
+		bookVersion, err := b.makeVersion(s, s.Versions[key])
+		if err != nil {
+			errs.Add(&VersionError{Version: key, Err: err})
+			continue
+		}
+
+		b.Versions[key] = *bookVersion
+	}
+
+	return errs.OrNil()
+}
+
+// makeVersion merges the docs and images of shared, and each version in the version's chain, into a version of the book
+func (b *Book) makeVersion(s *sources.Sources, version pages.Version) (*pages.Version, error) {
+
+	log.Print("Making version " + version.Version + "...")
+
+	var bookVersion = &pages.Version{
+		Version:  version.Version,
+		Base:     version.Base,
+		DestPath: b.Root.DestPath + "/" + b.Config.VersionPath(version.Version),
+		Docs:     make(map[string]pages.Doc),
+		Images:   make(map[string]pages.Asset),
+		Partials: make(map[string]pages.Doc),
+		Samples:  make(map[string]pages.Asset),
+	}
+
+	chain, err := versionChain(s, version.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	bookVersion.Vars, err = layerVars(s, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Print("Copying shared assets...")
+	//copy shared assets first
+	for key, doc := range s.Shared.Docs {
+		bookVersion.Docs[key] = doc
+	}
+
+	log.Print("Copying shared images...")
+	for key, image := range s.Shared.Images {
+		bookVersion.Images[key] = image
+	}
+
+	for key, partial := range s.Shared.Partials {
+		bookVersion.Partials[key] = partial
+	}
+
+	for key, sample := range s.Shared.Samples {
+		bookVersion.Samples[key] = sample
+	}
 
-		log.Print("Making version " + version.Version + "...")
+	//now copy assets for each version in the chain, ending with this one, and overwrite any with the same name
+	for _, layer := range chain {
+		log.Print("Copying version " + layer.Version + " assets...")
+		for _, key := range sortedKeys(layer.Docs) {
+			if existing, ok := bookVersion.Docs[key]; ok {
+				b.addOverride(version.Version, key, layer.Version, existing.Version)
+			}
+			bookVersion.Docs[key] = layer.Docs[key]
+		}
 
-		var bookVersion = &pages.Version{
-			Version:  version.Version,
-			DestPath: b.Root.DestPath + "/" + pages.ContentDirName + "/" + version.Version,
-			Docs:     make(map[string]pages.Doc),
+		log.Print("Copying version " + layer.Version + " images...")
+		for _, key := range sortedKeys(layer.Images) {
+			if existing, ok := bookVersion.Images[key]; ok {
+				b.addOverride(version.Version, b.Config.ImagePath()+"/"+key, layer.Version, existing.Version)
+			}
+			bookVersion.Images[key] = layer.Images[key]
 		}
 
-		log.Print("Copying shared assets...")
-		//copy shared assets first
-		for key, doc := range s.Shared.Docs {
-			bookVersion.Docs[key] = doc
+		for _, key := range sortedKeys(layer.Partials) {
+			if existing, ok := bookVersion.Partials[key]; ok {
+				b.addOverride(version.Version, b.Config.PartialsFolder+"/"+key, layer.Version, existing.Version)
+			}
+			bookVersion.Partials[key] = layer.Partials[key]
 		}
 
-		log.Print("Copying version assets...")
-		//now copy assets for this sharedVersion and overwrite any shared assets with the same name
-		for key, doc := range version.Docs {
-			bookVersion.Docs[key] = doc
+		for _, key := range sortedKeys(layer.Samples) {
+			if existing, ok := bookVersion.Samples[key]; ok {
+				b.addOverride(version.Version, b.Config.SamplesFolder+"/"+key, layer.Version, existing.Version)
+			}
+			bookVersion.Samples[key] = layer.Samples[key]
 		}
 
-		b.Versions[key] = *bookVersion
+		b.excludeFiles(layer, bookVersion)
+	}
+
+	err = checkImageCollisions(bookVersion)
+	if err != nil {
+		return nil, err
 	}
+
+	return bookVersion, nil
+}
+
+// checkImageCollisions checks that no two images in a version differ only by the case of their path.
+// Such images would overwrite each other on a case-insensitive file system.
+func checkImageCollisions(version *pages.Version) error {
+	seen := make(map[string]string)
+	for _, key := range sortedKeys(version.Images) {
+		folded := strings.ToLower(key)
+		if other, ok := seen[folded]; ok {
+			return fmt.Errorf("image %s collides with image %s", key, other)
+		}
+		seen[folded] = key
+	}
+	return nil
 }
 
 func (b *Book) MakeTOC(s *sources.Sources) error {
@@ -120,28 +305,22 @@ func (b *Book) MakeTOC(s *sources.Sources) error {
 		return err
 	}
 
+	entries.VersionOrder = b.Options.VersionOrder
 	orderedTocs := entries.Sort()
 
-	//create a workdir
-	b.Root.WorkDir, err = os.MkdirTemp(s.Root.SourcePath, "summary")
-
-	//write the summary
-	summary, err := os.Create(b.Root.WorkDir + "/" + pages.SummaryFileName)
+	//we hold the summary in memory, so that we never write to the sources
+	var summary bytes.Buffer
+	mg := newMarkdownGenerator(b.Config)
+	err = mg.GenerateSummary(orderedTocs, &summary)
 	if err != nil {
 		return err
 	}
-	defer summary.Close()
-
-	mg := newMarkdownGenerator()
-	err = mg.GenerateSummary(orderedTocs, summary)
 
+	b.Root.Summary = summary.Bytes()
+	b.Tocs = orderedTocs
 	return nil
 }
 
-func (b *Book) ClearWorkDir() error {
-	return os.RemoveAll(b.Root.WorkDir)
-}
-
 func (b *Book) buildEntries(s *sources.Sources) (*pages.VersionedToc, error) {
 
 	log.Print("Building TOC entries...")
@@ -149,45 +328,113 @@ func (b *Book) buildEntries(s *sources.Sources) (*pages.VersionedToc, error) {
 		Contents: make(map[string]*pages.Toc),
 	}
 
+	//if we can't load the shared entries we carry on without them, so that we report any errors in the versions too
+	var errs Errors
 	shared, err := b.loadSharedEntries(s)
 	if err != nil {
-		return nil, err
+		errs.Add(err)
+		shared = &pages.Toc{Sections: make(map[string]*pages.TOCSection)}
 	}
 
-	for _, version := range s.Versions {
+	for _, versionName := range sortedKeys(s.Versions) {
+		version := s.Versions[versionName]
 
-		versionEntries, err := b.buildVersionEntries(shared, version)
+		chain, err := versionChain(s, version.Version)
 		if err != nil {
-			return nil, err
+			errs.Add(&VersionError{Version: versionName, Err: err})
+			continue
+		}
+
+		versionEntries, err := b.buildVersionEntries(shared, chain)
+		if err != nil {
+			errs.Add(err)
+			continue
+		}
+
+		b.applyTombstones(chain, versionEntries)
+
+		err = b.addFrontMatterEntries(version.Version, versionEntries)
+		if err != nil {
+			errs.Add(err)
+			continue
 		}
 		summary.Contents[version.Version] = versionEntries
 	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
 	return &summary, nil
 }
 
 func (b *Book) loadSharedEntries(s *sources.Sources) (*pages.Toc, error) {
 
+	if s.Shared.TOC == nil {
+		return nil, b.missingToc("", s.Shared.SourcePath)
+	}
+
 	log.Print("Loading shared entries from " + s.Shared.TOC.SourcePath + "/" + s.Shared.TOC.Storage.Name() + "...")
-	file, err := os.ReadFile(s.Shared.TOC.SourcePath + "/" + s.Shared.TOC.Storage.Name())
+	return b.loadToc("", s.Shared.TOC)
+}
+
+// yamlLine finds the line number in an error from the yaml parser
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+// readToc reads and parses a .toc.yaml file, and nests its entries
+// It returns a TocParseError if the file cannot be read or parsed, with the line that failed if the parser tells us.
+func readToc(version string, doc *pages.Doc) (*pages.Toc, error) {
+	path := doc.SourcePath + "/" + doc.Storage.Name()
+	file, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatal(err)
-		return nil, err
+		return nil, &TocParseError{Version: version, Path: path, Err: err}
 	}
 
-	shared := pages.Toc{
+	toc := pages.Toc{
 		Sections: make(map[string]*pages.TOCSection),
 	}
 
-	err = yaml.Unmarshal(file, &shared)
+	err = yaml.Unmarshal(file, &toc)
 	if err != nil {
-		log.Fatal(err)
-		return nil, err
+		parseError := &TocParseError{Version: version, Path: path, Err: err}
+		if match := yamlLine.FindStringSubmatch(err.Error()); match != nil {
+			parseError.Line, _ = strconv.Atoi(match[1])
+		}
+		return nil, parseError
+	}
+
+	err = toc.Nest()
+	if err != nil {
+		return nil, &TocParseError{Version: version, Path: path, Err: err}
 	}
-	return &shared, nil
+	return &toc, nil
 }
 
-func (b *Book) buildVersionEntries(shared *pages.Toc, version pages.Version) (*pages.Toc, error) {
+// tocPath returns the path of the .toc.yaml file we expected to find in a folder, or an empty path if we don't
+// know the folder
+func (b *Book) tocPath(folder string) string {
+	if folder == "" {
+		return ""
+	}
+	return folder + "/" + b.Config.TocFile
+}
+
+// missingToc returns the error for a version, or shared, whose folder has no TOC file
+func (b *Book) missingToc(version string, folder string) *MissingTocError {
+	return &MissingTocError{Version: version, Path: b.tocPath(folder), File: b.Config.TocFile}
+}
+
+// sourceConfig returns the layout the sources were found with, or the default layout if they don't have one
+func sourceConfig(s *sources.Sources) *config.Config {
+	if s.Config == nil {
+		return config.Default()
+	}
+	return s.Config
+}
+
+// buildVersionEntries merges the shared TOC with the TOC of each version in the chain, ending with the version itself.
+func (b *Book) buildVersionEntries(shared *pages.Toc, chain []pages.Version) (*pages.Toc, error) {
 
+	version := chain[len(chain)-1]
 	log.Print("Building version entries for " + version.Version + "...")
 	//merge the shared and versioned information
 	versionedSections := pages.Toc{
@@ -196,9 +443,15 @@ func (b *Book) buildVersionEntries(shared *pages.Toc, version pages.Version) (*p
 
 	b.addSharedSections(shared, &versionedSections)
 
-	err := b.addVersionedSections(version, &versionedSections)
-	if err != nil {
-		return nil, err
+	for _, layer := range chain {
+		if layer.ReadMe != nil {
+			versionedSections.LandingPage = layer.ReadMe.Storage.Name()
+		}
+
+		err := b.addVersionedSections(layer, &versionedSections)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return &versionedSections, nil
 }
@@ -207,41 +460,42 @@ func (b *Book) addSharedSections(shared *pages.Toc, versionedEntries *pages.Toc)
 
 	log.Print("Adding shared TOC sections...")
 	//add the shared information for each configuration
-	for key, section := range shared.Sections {
+	for _, key := range sortedKeys(shared.Sections) {
+		section := shared.Sections[key]
 		versionedSection := pages.TOCSection{
 			Order: section.Order,
 		}
-		versionedSectionEntries := make([]pages.TOCEntry, 0)
-		for _, sharedEntry := range section.Entries {
-			versionedSectionEntries = append(versionedSectionEntries, sharedEntry)
-		}
-		versionedSection.Entries = versionedSectionEntries
+		versionedSection.Entries = copyEntries(section.Entries)
 		versionedEntries.Sections[key] = &versionedSection
 	}
 }
 
 func (b *Book) addVersionedSections(version pages.Version, versionedTOC *pages.Toc) error {
 
-	log.Print("Loading versioned TOC sections for " + version.TOC.SourcePath + "/" + version.TOC.Storage.Name() + "...")
-	//read the versioned information
-	file, err := os.ReadFile(version.TOC.SourcePath + "/" + version.TOC.Storage.Name())
-	if err != nil {
-		log.Fatal(err)
-		return err
-	}
-
-	versioned := pages.Toc{
-		Sections: make(map[string]*pages.TOCSection),
+	if version.TOC == nil {
+		return b.missingToc(version.Version, version.SourcePath)
 	}
 
-	err = yaml.Unmarshal(file, &versioned)
+	log.Print("Loading versioned TOC sections for " + version.TOC.SourcePath + "/" + version.TOC.Storage.Name() + "...")
+	//read the versioned information
+	versioned, err := b.loadToc(version.Version, version.TOC)
 	if err != nil {
-		log.Fatal(err)
 		return err
 	}
 
 	//for each section in the version
-	for v, i := range versioned.Sections {
+	for _, v := range sortedKeys(versioned.Sections) {
+		i := versioned.Sections[v]
+		//a tombstone removes the section, and the files of its entries, that we would otherwise inherit
+		if i.Remove {
+			if existing, ok := versionedTOC.Sections[v]; ok {
+				log.Print("Version " + version.Version + " removes section " + v + "...")
+				versionedTOC.Removed = append(versionedTOC.Removed, entryFiles(existing.Entries)...)
+				delete(versionedTOC.Sections, v)
+			}
+			continue
+		}
+
 		//iterate over the shared toc sections we have already added
 		sectionExists := false
 		for s, j := range versionedTOC.Sections {
@@ -252,41 +506,78 @@ func (b *Book) addVersionedSections(version pages.Version, versionedTOC *pages.T
 				j.Order = i.Order
 
 				//we already have this section and map values need to be re-assigned not changed
-				var entries = make([]pages.TOCEntry, 0)
-
-				//add the shared entries
-				for _, sharedEntry := range j.Entries {
-					entries = append(entries, sharedEntry)
-				}
-
-				//iterate over versioned entries
-				for _, versionedEntry := range i.Entries {
-					found := false
-					for k, se := range entries {
-						//if we have a matching shared entry replace it with the versioned one
-						if se.Name == versionedEntry.Name {
-							entries[k] = versionedEntry
-							found = true
-							break
-						}
-					}
-					//it was a new entry add it
-					if !found {
-						entries = append(entries, versionedEntry)
-					}
-				}
-
-				j.Entries = entries
+				var removed []string
+				j.Entries, removed = mergeEntries(j.Entries, i.Entries)
+				versionedTOC.Removed = append(versionedTOC.Removed, removed...)
 			}
 		}
 		//if it is not an existing section, just add it
 		if !sectionExists {
+			i.Entries, _ = mergeEntries(nil, i.Entries)
 			versionedTOC.Sections[v] = i
 		}
 	}
 	return nil
 }
 
+// mergeEntries merges versioned entries into shared entries, at every level of nesting
+// A versioned entry with the same name as a shared entry replaces it, but the children of both are merged.
+// A versioned entry that is a tombstone removes the shared entry, and its children, with the same name.
+// A versioned entry that does not match a shared entry is added.
+// It returns the merged entries, and the files of any entries that were removed.
+func mergeEntries(shared []pages.TOCEntry, versioned []pages.TOCEntry) (entries []pages.TOCEntry, removed []string) {
+	entries = copyEntries(shared)
+
+	for _, versionedEntry := range versioned {
+		found := false
+		for k, se := range entries {
+			//if we have a matching shared entry replace it with the versioned one
+			if se.Name == versionedEntry.Name {
+				found = true
+				if versionedEntry.Remove {
+					removed = append(removed, entryFiles(entries[k:k+1])...)
+					entries = append(entries[:k], entries[k+1:]...)
+					break
+				}
+
+				children, removedChildren := mergeEntries(se.Children, versionedEntry.Children)
+				removed = append(removed, removedChildren...)
+				entries[k] = versionedEntry
+				entries[k].Children = children
+				break
+			}
+		}
+		//it was a new entry add it, unless it is a tombstone for an entry we don't have
+		if !found && !versionedEntry.Remove {
+			entry := versionedEntry
+			entry.Children, _ = mergeEntries(nil, versionedEntry.Children)
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, removed
+}
+
+// copyEntries makes a deep copy of entries, so that sorting one version's entries does not change another's
+func copyEntries(entries []pages.TOCEntry) []pages.TOCEntry {
+	copied := make([]pages.TOCEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Children = copyEntries(entry.Children)
+		copied = append(copied, entry)
+	}
+	return copied
+}
+
+// sortedKeys returns the keys of a map in order, so that we always work through the map in the same order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // copyFile copies a file from sourcePath to destPath
 // It takes two string arguments: sourcePath and destPath
 // It creates the destination file if it does not exist
@@ -309,8 +600,6 @@ func copyFile(sourcePath string, destPath string, fileName string) (err error) {
 		}
 	}
 
-	log.Print("Copying " + sourcePath + "/" + fileName + " to " + destPath + "/" + fileName + "...")
-
 	w, err := os.Create(destPath + "/" + fileName)
 	if err != nil {
 		return err
@@ -1,28 +1,25 @@
 package book
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/brightercommand/Rewind/internal/config"
 	"github.com/brightercommand/Rewind/internal/pages"
 	"github.com/brightercommand/Rewind/internal/sources"
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/md"
 	"github.com/gomarkdown/markdown/parser"
-	"github.com/google/uuid"
 	"os"
 	"strings"
 	"testing"
 )
 
 func TestFileCopy(t *testing.T) {
-	mydir, err := os.Getwd()
-	if err != nil {
-		t.Errorf("Error getting working directory: %s", err)
-	}
-
-	sourcePath := strings.Replace(mydir, "internal/book", "test/filecopy", 1)
-	destPath := strings.Replace(mydir, "internal/book", fmt.Sprintf("test/filecopy/%s", uuid.New().String()), 1)
+	sourcePath := fixturePath(t, "filecopy")
+	destPath := t.TempDir() + "/filecopy"
 
-	err = copyFile(sourcePath, destPath, "DocumentOne.md")
+	err := copyFile(sourcePath, destPath, "DocumentOne.md")
 	if err != nil {
 		t.Errorf("Error copying file: %s", err)
 	}
@@ -31,18 +28,10 @@ func TestFileCopy(t *testing.T) {
 	if err != nil {
 		t.Errorf("Error copying file: %s", err)
 	}
-
-	// Remove the directory
-	err = os.RemoveAll(destPath)
-	if err != nil {
-		t.Errorf("Error removing directory: %s", err)
-	}
-
 }
 
 // TestBookBuilder tests the book builder.
 // It creates a fake directory structure and then runs the book builder.
-// NOTE: Test can fail because ordering is not guaranteed in maps. A re-run typically fixes the issue.
 func TestBookBuilder(t *testing.T) {
 
 	mydir, err := os.Getwd()
@@ -51,10 +40,10 @@ func TestBookBuilder(t *testing.T) {
 	}
 
 	sourcePath := strings.Replace(mydir, "internal/book", "test/source", 1)
-	destPath := strings.Replace(mydir, "internal/book", fmt.Sprintf("test/docs/%s", uuid.New().String()), 1)
+	destPath := t.TempDir() + "/book"
 
 	var src = sources.SourceTestDataBuilder(sourcePath, mydir)
-	book, err := MakeBook(src, destPath)
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
 	if err != nil {
 		t.Errorf("Error building book: %s", err)
 	}
@@ -70,12 +59,6 @@ func TestBookBuilder(t *testing.T) {
 	checkVersion10(t, book, destPath, sourcePath)
 
 	checkTOC(t, book, sourcePath, destPath)
-
-	// Remove the temp directory used for the summary file
-	err = book.ClearWorkDir()
-	if err != nil {
-		t.Errorf("Error removing directory: %s", err)
-	}
 }
 
 func checkBookRoot(t *testing.T, book *Book, destPath string, sources *sources.Sources, sourcePath string) {
@@ -158,8 +141,8 @@ func checkVersion10(t *testing.T, book *Book, destPath string, sourcePath string
 		t.Errorf("Expected %s, got %s", fmt.Sprintf("%s/contents/10", destPath), versionTen.DestPath)
 	}
 
-	if len(versionTen.Docs) != 4 {
-		t.Errorf("Expected 3 docs, got %d", len(versionTen.Docs))
+	if len(versionTen.Docs) != 5 {
+		t.Errorf("Expected 5 docs, got %d", len(versionTen.Docs))
 	}
 
 	expectedSourcePath := fmt.Sprintf("%s/10", sourcePath)
@@ -215,14 +198,11 @@ func checkTOC(t *testing.T, book *Book, sourcePath string, destPath string) {
 		t.Errorf("Expected %s, got %s", sourcePath, book.Root.SourcePath)
 	}
 
-	if book.Root.WorkDir == "" {
-		t.Errorf("Expected a WorkDir for the summary file")
+	if len(book.Root.Summary) == 0 {
+		t.Errorf("Expected a summary file")
 	}
 
-	mdFile, err := os.ReadFile(book.Root.WorkDir + "/" + pages.SummaryFileName)
-	if err != nil {
-		t.Errorf("Error reading file: %s", err)
-	}
+	mdFile := book.Root.Summary
 
 	ext := parser.CommonExtensions | parser.OrderedListStart
 	ps := parser.NewWithExtensions(ext)
@@ -231,7 +211,7 @@ func checkTOC(t *testing.T, book *Book, sourcePath string, destPath string) {
 	got := markdown.Render(doc, renderer)
 	toc := fmt.Sprintf("%s", got)
 
-	expectedTOC := "## 9\n### Brighter Configuration\n* [Document One](/contents/9/DocumentOne.md)\n* [Document Two](/contents/9/DocumentTwo.md)\n### Darker Configuration\n* [Document Four](/contents/9/DocumentFour.md)\n* [Document Three](/contents/9/DocumentThree.md)\n## 10\n### Brighter Configuration\n* [Document One](/contents/10/DocumentOne.md)\n* [Document Two](/contents/10/DocumentTwo.md)\n### Darker Configuration\n* [Document Four](/contents/10/DocumentFour.md)\n* [Document Three](/contents/10/DocumentThree.md)\n"
+	expectedTOC := "## 9\n### Brighter Configuration\n* [Document One](/contents/9/DocumentOne.md)\n* [Document Two](/contents/9/DocumentTwo.md)\n### Darker Configuration\n* [Document Four](/contents/9/DocumentFour.md)\n* [Document Three](/contents/9/DocumentThree.md)\n## 10\n* [10](/contents/10/README.md)\n### Brighter Configuration\n* [Document One](/contents/10/DocumentOne.md)\n* [Document Four](/contents/10/DocumentFour.md)\n* [Document Two](/contents/10/DocumentTwo.md)\n### Darker Configuration\n* [Document Four](/contents/10/DocumentFour.md)\n* [Document Three](/contents/10/DocumentThree.md)\n"
 	if toc != expectedTOC {
 		t.Errorf("Expected %s, got %s", expectedTOC, toc)
 	}
@@ -239,42 +219,23 @@ func checkTOC(t *testing.T, book *Book, sourcePath string, destPath string) {
 }
 
 func TestBookCreation(t *testing.T) {
-
-	myDir, err := os.Getwd()
-	if err != nil {
-		t.Errorf("Error getting working directory: %s", err)
-	}
-
-	sourcePath := strings.Replace(myDir, "internal/book", "test/source", 1)
-	destPath := strings.Replace(myDir, "internal/book", fmt.Sprintf("test/docs/%s", uuid.New().String()), 1)
-
-	src := sources.NewSources()
-	err = src.FindFromPath(sourcePath)
-	if err != nil {
-		t.Errorf("Error finding sources: %s", err)
-	}
-
-	book, err := MakeBook(src, destPath)
-	if err != nil {
-		t.Errorf("Error building book: %s", err)
-	}
-
-	err = book.Publish()
-	if err != nil {
-		t.Errorf("Error creating book: %s", err)
-	}
+	destPath := publishFixture(t, "source")
 
 	entries, err := os.ReadDir(destPath)
 	if err != nil {
 		t.Errorf("Error reading directory: %s", err)
 	}
 
-	var summaryFound, gitbookFound, v9Found, v10Found bool
+	var summaryFound, gitbookFound, readMeFound, contributingFound, v9Found, v10Found bool
 	for _, entry := range entries {
 		if entry.Name() == "SUMMARY.md" {
 			summaryFound = true
 		} else if entry.Name() == ".gitbook.yaml" {
 			gitbookFound = true
+		} else if entry.Name() == "README.md" {
+			readMeFound = true
+		} else if entry.Name() == "CONTRIBUTING.md" {
+			contributingFound = true
 		} else if entry.IsDir() {
 			if entry.Name() == "contents" {
 				contents, err := os.ReadDir(destPath + "/contents")
@@ -288,7 +249,7 @@ func TestBookCreation(t *testing.T) {
 							t.Errorf("Error reading directory: %s", err)
 							v9Found = false
 						} else {
-							v9Found = findFiles(v9entries)
+							v9Found = findFiles(v9entries) && findImages(t, fmt.Sprintf("%s/contents/9", destPath), "ImageThree.png", "ImageFour.png")
 						}
 					} else if entry.Name() == "10" {
 						v10entries, err := os.ReadDir(fmt.Sprintf("%s/contents/10", destPath))
@@ -296,7 +257,7 @@ func TestBookCreation(t *testing.T) {
 							t.Errorf("Error reading directory: %s", err)
 							v10Found = false
 						} else {
-							v10Found = findFiles(v10entries)
+							v10Found = findFiles(v10entries) && findImages(t, fmt.Sprintf("%s/contents/10", destPath), "ImageOne.png", "ImageThree.png")
 						}
 					}
 				}
@@ -312,6 +273,14 @@ func TestBookCreation(t *testing.T) {
 		t.Errorf("Expected .gitbook.yaml")
 	}
 
+	if readMeFound == false {
+		t.Errorf("Expected README.md")
+	}
+
+	if contributingFound == false {
+		t.Errorf("Expected CONTRIBUTING.md from the root folder")
+	}
+
 	if v9Found == false {
 		t.Errorf("Expected 9")
 	}
@@ -319,12 +288,6 @@ func TestBookCreation(t *testing.T) {
 	if v10Found == false {
 		t.Errorf("Expected 10")
 	}
-
-	// Remove the directory
-	err = os.RemoveAll(destPath)
-	if err != nil {
-		t.Errorf("Error removing directory: %s", err)
-	}
 }
 
 func findFiles(entries []os.DirEntry) bool {
@@ -342,3 +305,298 @@ func findFiles(entries []os.DirEntry) bool {
 	}
 	return documentOneFound && documentTwoFound && documentThreeFound
 }
+
+func findImages(t *testing.T, versionPath string, versionImages ...string) bool {
+	images := append([]string{"ImageOne.png", "ImageTwo.png", "diagrams/ImageFive.png"}, versionImages...)
+	for _, image := range images {
+		if _, err := os.Stat(versionPath + "/_static/images/" + image); err != nil {
+			t.Errorf("Expected image %s: %s", image, err)
+			return false
+		}
+	}
+	return true
+}
+
+func TestImageCollisions(t *testing.T) {
+	version := &pages.Version{
+		Version: "10",
+		Images: map[string]pages.Asset{
+			"diagrams/Outbox.png": {},
+			"Diagrams/outbox.png": {},
+		},
+	}
+
+	err := checkImageCollisions(version)
+	if err == nil {
+		t.Errorf("Expected a collision between diagrams/Outbox.png and Diagrams/outbox.png")
+	}
+
+	delete(version.Images, "Diagrams/outbox.png")
+	err = checkImageCollisions(version)
+	if err != nil {
+		t.Errorf("Expected no collision, got %s", err)
+	}
+}
+
+func TestMergeNestedEntries(t *testing.T) {
+	shared := []pages.TOCEntry{
+		{
+			Name: "Document One",
+			File: "DocumentOne.md",
+			Children: []pages.TOCEntry{
+				{Name: "Document Two", File: "DocumentTwo.md", Order: 100},
+				{Name: "Document Three", File: "DocumentThree.md", Order: 200},
+			},
+		},
+	}
+
+	versioned := []pages.TOCEntry{
+		{
+			Name: "Document One",
+			File: "DocumentOneV10.md",
+			Children: []pages.TOCEntry{
+				{Name: "Document Two", File: "DocumentTwoV10.md", Order: 100},
+				{Name: "Document Four", File: "DocumentFour.md", Order: 300},
+			},
+		},
+	}
+
+	merged, removed := mergeEntries(shared, versioned)
+
+	if len(merged) != 1 || merged[0].File != "DocumentOneV10.md" {
+		t.Fatalf("Expected Document One to be replaced by the versioned entry, got %v", merged)
+	}
+
+	children := merged[0].Children
+	if len(children) != 3 {
+		t.Fatalf("Expected 3 children, got %d", len(children))
+	}
+
+	if children[0].File != "DocumentTwoV10.md" {
+		t.Errorf("Expected the versioned Document Two, got %s", children[0].File)
+	}
+
+	if children[1].File != "DocumentThree.md" {
+		t.Errorf("Expected the shared Document Three, got %s", children[1].File)
+	}
+
+	if children[2].File != "DocumentFour.md" {
+		t.Errorf("Expected the new Document Four, got %s", children[2].File)
+	}
+
+	if shared[0].Children[0].File != "DocumentTwo.md" {
+		t.Errorf("Expected the shared entries to be unchanged, got %s", shared[0].Children[0].File)
+	}
+
+	if len(removed) != 0 {
+		t.Errorf("Expected no removed files, got %v", removed)
+	}
+}
+
+func TestMergeRemovesTombstones(t *testing.T) {
+	shared := []pages.TOCEntry{
+		{
+			Name: "Document One",
+			File: "DocumentOne.md",
+			Children: []pages.TOCEntry{
+				{Name: "Document Two", File: "DocumentTwo.md"},
+			},
+		},
+		{Name: "Document Three", File: "DocumentThree.md"},
+	}
+
+	versioned := []pages.TOCEntry{
+		{Name: "Document One", Remove: true},
+		{Name: "Document Four", Remove: true},
+	}
+
+	merged, removed := mergeEntries(shared, versioned)
+
+	if len(merged) != 1 || merged[0].Name != "Document Three" {
+		t.Errorf("Expected only Document Three to remain, got %v", merged)
+	}
+
+	if len(removed) != 2 || removed[0] != "DocumentOne.md" || removed[1] != "DocumentTwo.md" {
+		t.Errorf("Expected DocumentOne.md and DocumentTwo.md to be removed, got %v", removed)
+	}
+}
+
+func TestTombstones(t *testing.T) {
+	src, _ := findFixture(t, "tombstones")
+	destPath := t.TempDir() + "/book"
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	if len(book.Versions["9"].Docs) != 4 {
+		t.Errorf("Expected 4 docs in 9, got %d", len(book.Versions["9"].Docs))
+	}
+
+	versionTen := book.Versions["10"]
+	if len(versionTen.Docs) != 1 {
+		t.Errorf("Expected 1 doc in 10, got %d", len(versionTen.Docs))
+	}
+
+	if _, ok := versionTen.Docs["Introduction.md"]; !ok {
+		t.Errorf("Expected Introduction.md in 10")
+	}
+
+	summary := book.Root.Summary
+
+	expectedTen := "## 10\n\n### Overview\n\n * [Introduction](/contents/10/Introduction.md)\n\n"
+	expectedNine := "## 9\n\n### Overview\n\n * [Introduction](/contents/9/Introduction.md)\n * [Legacy](/contents/9/Legacy.md)\n * [Policy](/contents/9/Policy.md)\n\n### Deprecated\n\n * [Old](/contents/9/Old.md)\n"
+	if !strings.Contains(string(summary), expectedNine) {
+		t.Errorf("Expected summary to contain %s, got %s", expectedNine, summary)
+	}
+
+	if !strings.HasSuffix(string(summary), expectedTen) {
+		t.Errorf("Expected removed entries to be dropped from 10, expected %s, got %s", expectedTen, summary)
+	}
+}
+
+func TestVersionInheritance(t *testing.T) {
+	src, sourcePath := findFixture(t, "inheritance")
+	destPath := t.TempDir() + "/book"
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	versionTen := book.Versions["10"]
+	expected := map[string]string{
+		"Introduction.md": sourcePath + "/shared",
+		"Support.md":      sourcePath + "/8",
+		"Outbox.md":       sourcePath + "/9",
+		"Inbox.md":        sourcePath + "/10",
+	}
+
+	if len(versionTen.Docs) != len(expected) {
+		t.Errorf("Expected %d docs in 10, got %d", len(expected), len(versionTen.Docs))
+	}
+
+	for name, expectedSourcePath := range expected {
+		doc, ok := versionTen.Docs[name]
+		if !ok {
+			t.Errorf("Expected %s in 10", name)
+		} else if doc.SourcePath != expectedSourcePath {
+			t.Errorf("Expected %s from %s, got %s", name, expectedSourcePath, doc.SourcePath)
+		}
+	}
+
+	if len(book.Versions["8"].Docs) != 3 {
+		t.Errorf("Expected 3 docs in 8, got %d", len(book.Versions["8"].Docs))
+	}
+
+	summary := book.Root.Summary
+
+	expectedTen := "## 10\n\n### Overview\n\n * [Introduction](/contents/10/Introduction.md)\n * [Outbox](/contents/10/Outbox.md)\n * [Inbox](/contents/10/Inbox.md)\n\n### Long Term Support\n\n * [Support](/contents/10/Support.md)\n"
+	if !strings.Contains(string(summary), expectedTen) {
+		t.Errorf("Expected summary to contain %s, got %s", expectedTen, summary)
+	}
+}
+
+func TestVersionInheritanceErrors(t *testing.T) {
+	src := sources.NewSources()
+	src.Versions["8"] = pages.Version{Version: "8", Base: "10"}
+	src.Versions["9"] = pages.Version{Version: "9", Base: "8"}
+	src.Versions["10"] = pages.Version{Version: "10", Base: "9"}
+	src.Versions["11"] = pages.Version{Version: "11", Base: "12"}
+
+	_, err := versionChain(src, "10")
+	if err == nil || !strings.Contains(err.Error(), "10 -> 9 -> 8 -> 10") {
+		t.Errorf("Expected an inheritance cycle, got %v", err)
+	}
+
+	_, err = versionChain(src, "11")
+	if err == nil || !strings.Contains(err.Error(), "base version 12 of version 11 does not exist") {
+		t.Errorf("Expected a missing base version, got %v", err)
+	}
+}
+
+func TestMakeBookReportsEveryError(t *testing.T) {
+	src, sourcePath := findFixture(t, "invalid")
+	destPath := t.TempDir() + "/book"
+
+	_, err := MakeBook(context.Background(), src, destPath, Options{})
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected Errors, got %v", err)
+	}
+
+	var missingToc *MissingTocError
+	var parseError *TocParseError
+	for _, e := range errs {
+		if errors.As(e, &missingToc) && missingToc.Version != "10" {
+			t.Errorf("Expected the missing TOC in version 10, got %s", missingToc.Version)
+		}
+		if errors.As(e, &parseError) && (parseError.Version != "9" || parseError.Path != sourcePath+"/9/.toc.yaml") {
+			t.Errorf("Expected the parse error in version 9, got %s", parseError.Error())
+		}
+	}
+
+	if missingToc == nil || parseError == nil {
+		t.Errorf("Expected both a missing TOC and a parse error, got %s", err)
+	}
+}
+
+func TestBookWithConfiguredLayout(t *testing.T) {
+	sourcePath := fixturePath(t, "config")
+	destPath := t.TempDir() + "/book"
+
+	cfg, err := config.Load(sourcePath + "/" + config.FileName)
+	if err != nil {
+		t.Errorf("Error loading configuration: %s", err)
+		return
+	}
+
+	src := sources.NewSources()
+	src.Config = cfg
+	err = src.FindFromPath(sourcePath)
+	if err != nil {
+		t.Errorf("Error finding sources: %s", err)
+	}
+
+	if _, ok := src.Versions["drafts"]; ok {
+		t.Errorf("Expected drafts to be skipped as it does not match the version pattern")
+	}
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Errorf("Error creating book: %s", err)
+	}
+
+	for _, file := range []string{
+		"versions/v9/Introduction.md",
+		"versions/v9/Nine.md",
+		"versions/v9/assets/img/Logo.png",
+		"versions/v10/Introduction.md",
+		"versions/v10/Ten.md",
+	} {
+		if _, err := os.Stat(destPath + "/" + file); err != nil {
+			t.Errorf("Expected %s to be published: %s", file, err)
+		}
+	}
+
+	if _, err := os.Stat(destPath + "/versions/v10/assets/img/Logo.png"); err == nil {
+		t.Errorf("Expected v10 to exclude assets/img/Logo.png")
+	}
+
+	summary, err := os.ReadFile(destPath + "/SUMMARY.md")
+	if err != nil {
+		t.Errorf("Error reading SUMMARY.md: %s", err)
+	}
+
+	if !strings.Contains(string(summary), "(/versions/v9/Nine.md)") {
+		t.Errorf("Expected SUMMARY.md to link to the configured layout, got:\n%s", summary)
+	}
+}
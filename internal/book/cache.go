@@ -0,0 +1,199 @@
+package book
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"gopkg.in/yaml.v3"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cacheFolderName is the folder, in the user's cache folder, that holds a build cache for each destination
+const cacheFolderName = "rewind"
+
+// buildCache What we know about the last build to a destination, so that we can skip writing files that have not changed.
+// Sources records the hash of each source file, so that we only hash a source again if its size or modification time
+// has changed. Files records the hash of each file we wrote, and its size and modification time when we wrote it, so
+// that we can tell if it has been changed since.
+// The cache is safe to use from more than one goroutine at once.
+type buildCache struct {
+	mu       sync.Mutex
+	path     string
+	used     map[string]bool
+	DestPath string                `yaml:"destPath"`
+	Sources  map[string]cacheEntry `yaml:"sources"`
+	Files    map[string]cacheEntry `yaml:"files"`
+}
+
+// cacheEntry The hash of a file, and the size and modification time it had when we hashed it
+type cacheEntry struct {
+	Hash    string    `yaml:"sha256"`
+	Size    int64     `yaml:"size"`
+	ModTime time.Time `yaml:"modTime"`
+}
+
+// matches whether a file still has the size and modification time it had when we hashed it
+func (e cacheEntry) matches(info os.FileInfo) bool {
+	return e.Size == info.Size() && e.ModTime.Equal(info.ModTime())
+}
+
+// loadCache loads the build cache for the book's destination.
+// The cache is kept in cacheDir, or the user's cache folder if that is empty, keyed by the path of the destination.
+// If we have no cache for the destination, or cannot read it, we start with an empty one. If we have no cacheDir, and
+// the user has no cache folder, as in some CI containers, we start with an empty cache that we do not save, as the
+// cache only saves us work.
+func loadCache(cacheDir string, destPath string) (*buildCache, error) {
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			log.Print("Not keeping a build cache: " + err.Error())
+			return newBuildCache(), nil
+		}
+		cacheDir = filepath.Join(userCache, cacheFolderName)
+	}
+
+	destPath, err := resolvePath(destPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sha256.Sum256([]byte(destPath))
+	cache := newBuildCache()
+	cache.path = filepath.Join(cacheDir, hex.EncodeToString(key[:])+".yaml")
+	cache.DestPath = destPath
+
+	file, err := os.ReadFile(cache.path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		log.Print("Ignoring the build cache " + cache.path + " as it cannot be read: " + err.Error())
+		return cache, nil
+	}
+
+	loaded := newBuildCache()
+	loaded.path = cache.path
+	err = yaml.Unmarshal(file, loaded)
+	if err != nil || loaded.DestPath != destPath {
+		log.Print("Ignoring the build cache " + cache.path + " as it cannot be read...")
+		return cache, nil
+	}
+	return loaded, nil
+}
+
+// newBuildCache returns an empty build cache, that is not saved anywhere
+func newBuildCache() *buildCache {
+	return &buildCache{
+		used:    make(map[string]bool),
+		Sources: make(map[string]cacheEntry),
+		Files:   make(map[string]cacheEntry),
+	}
+}
+
+// save writes the build cache, unless it is not saved anywhere
+func (c *buildCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	file, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, file, 0644)
+}
+
+// contentHash returns the hash of what we would write for an output.
+// We only hash a source file again if it has changed size or modification time since we last hashed it.
+func (c *buildCache) contentHash(out output) (string, error) {
+	if out.Generated {
+		hash := sha256.Sum256(out.Content)
+		return hex.EncodeToString(hash[:]), nil
+	}
+
+	sourcePath := out.SourcePath + "/" + out.FileName
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.used[sourcePath] = true
+	entry, ok := c.Sources[sourcePath]
+	c.mu.Unlock()
+	if ok && entry.matches(info) {
+		return entry.Hash, nil
+	}
+
+	hash, err := hashFile(sourcePath)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.Sources[sourcePath] = cacheEntry{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}
+	c.mu.Unlock()
+	return hash, nil
+}
+
+// keep records that we still read the source of an output, though we did not hash it again, so we do not forget it
+func (c *buildCache) keep(out output) {
+	if out.Generated {
+		return
+	}
+
+	c.mu.Lock()
+	c.used[out.SourcePath+"/"+out.FileName] = true
+	c.mu.Unlock()
+}
+
+// unchanged whether the file at the output's path in the destination is the one we wrote last time, and what we wrote
+// then has the given hash, so that we do not need to write it again
+func (c *buildCache) unchanged(destPath string, out output, hash string) bool {
+	c.mu.Lock()
+	entry, ok := c.Files[out.Path]
+	c.mu.Unlock()
+	if !ok || entry.Hash != hash {
+		return false
+	}
+
+	info, err := os.Stat(destPath + "/" + out.Path)
+	return err == nil && entry.matches(info)
+}
+
+// written records a file we have written to the destination, with the hash of what we wrote
+func (c *buildCache) written(destPath string, path string, hash string) error {
+	info, err := os.Stat(destPath + "/" + path)
+	if err != nil {
+		return err
+	}
+	c.Files[path] = cacheEntry{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}
+	return nil
+}
+
+// prune forgets the files we no longer write, and the sources we no longer read
+func (c *buildCache) prune(manifest *Manifest) {
+	for path := range c.Sources {
+		if !c.used[path] {
+			delete(c.Sources, path)
+		}
+	}
+
+	current := make(map[string]bool)
+	for _, entry := range manifest.Files {
+		current[entry.Path] = true
+	}
+	for path := range c.Files {
+		if !current[path] {
+			delete(c.Files, path)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package book
+
+import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain keeps the build caches of the tests out of the user's cache folder
+func TestMain(m *testing.M) {
+	cacheDir, err := os.MkdirTemp("", "rewind-cache")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	code := m.Run()
+
+	_ = os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
+func TestIncrementalPublish(t *testing.T) {
+	//we change the sources, so work on a copy of them
+	sourcePath := t.TempDir() + "/source"
+	copyTree(t, fixturePath(t, "source"), sourcePath)
+	destPath := t.TempDir() + "/book"
+	cacheDir := t.TempDir()
+
+	publish := func(opts Options) {
+		src := sources.NewSources()
+		err := src.FindFromPath(sourcePath)
+		if err != nil {
+			t.Fatalf("Error finding sources: %s", err)
+		}
+
+		opts.CacheDir = cacheDir
+		book, err := MakeBook(context.Background(), src, destPath, opts)
+		if err != nil {
+			t.Fatalf("Error building book: %s", err)
+		}
+
+		err = book.Publish(context.Background())
+		if err != nil {
+			t.Fatalf("Error creating book: %s", err)
+		}
+	}
+
+	publish(Options{})
+	first := statTree(t, destPath)
+
+	//change a shared doc, and the order of a section of a version's TOC
+	err := os.WriteFile(sourcePath+"/shared/DocumentThree.md", []byte("# Document Three, changed\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+	toc, err := os.ReadFile(sourcePath + "/9/.toc.yaml")
+	if err != nil {
+		t.Fatalf("Error reading file: %s", err)
+	}
+	err = os.WriteFile(sourcePath+"/9/.toc.yaml", []byte(strings.Replace(string(toc), "order: 10", "order: 30", 1)), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+
+	//and change a published file behind our back
+	err = os.WriteFile(destPath+"/contents/10/DocumentTwo.md", []byte("# Changed in the book\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+
+	publish(Options{})
+	second := statTree(t, destPath)
+
+	rewritten := map[string]bool{
+		"SUMMARY.md":                   true,
+		"contents/9/DocumentThree.md":  true,
+		"contents/10/DocumentThree.md": true,
+		"contents/10/DocumentTwo.md":   true,
+		ManifestFileName:               true,
+	}
+	for path, info := range second {
+		if rewritten[path] == os.SameFile(first[path], info) {
+			t.Errorf("Expected %s to be rewritten: %t", path, rewritten[path])
+		}
+	}
+
+	content, err := os.ReadFile(destPath + "/contents/10/DocumentTwo.md")
+	if err != nil || strings.Contains(string(content), "Changed in the book") {
+		t.Errorf("Expected contents/10/DocumentTwo.md to be restored, got %q, %v", content, err)
+	}
+
+	//without the cache we write every file
+	publish(Options{NoCache: true})
+	third := statTree(t, destPath)
+	for path, info := range third {
+		if os.SameFile(second[path], info) {
+			t.Errorf("Expected %s to be rewritten without the cache", path)
+		}
+	}
+}
+
+func TestRootFolderReadMeWinsWhenWritingInParallel(t *testing.T) {
+	//the sources already have a README.md at the top, so add one to the root folder too
+	sourcePath := t.TempDir() + "/source"
+	copyTree(t, fixturePath(t, "source"), sourcePath)
+	err := os.WriteFile(sourcePath+"/root/README.md", []byte("# From the root folder\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+	destPath := t.TempDir() + "/book"
+	cacheDir := t.TempDir()
+
+	publish := func() {
+		src := sources.NewSources()
+		err := src.FindFromPath(sourcePath)
+		if err != nil {
+			t.Fatalf("Error finding sources: %s", err)
+		}
+
+		book, err := MakeBook(context.Background(), src, destPath, Options{Jobs: 8, CacheDir: cacheDir})
+		if err != nil {
+			t.Fatalf("Error building book: %s", err)
+		}
+
+		err = book.Publish(context.Background())
+		if err != nil {
+			t.Fatalf("Error creating book: %s", err)
+		}
+	}
+
+	publish()
+	first := statTree(t, destPath)
+
+	content, err := os.ReadFile(destPath + "/README.md")
+	if err != nil || string(content) != "# From the root folder\n" {
+		t.Errorf("Expected the README.md from the root folder, got %q, %v", content, err)
+	}
+
+	manifest, err := readManifest(destPath)
+	if err != nil {
+		t.Fatalf("Error reading manifest: %s", err)
+	}
+	count := 0
+	for _, entry := range manifest.Files {
+		if entry.Path == "README.md" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected README.md once in the manifest, got %d", count)
+	}
+
+	//with nothing changed, the cache skips the README.md rather than writing it again
+	publish()
+	second := statTree(t, destPath)
+	if !os.SameFile(first["README.md"], second["README.md"]) {
+		t.Errorf("Expected README.md not to be rewritten")
+	}
+}
+
+func TestPublishWithoutUserCacheFolder(t *testing.T) {
+	//as in a CI container, where there is neither $XDG_CACHE_HOME nor $HOME
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("HOME", "")
+	if _, err := os.UserCacheDir(); err == nil {
+		t.Skip("this platform finds the user's cache folder without $HOME")
+	}
+
+	src, _ := findFixture(t, "source")
+	destPath := t.TempDir() + "/book"
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Expected to publish without a build cache, got %s", err)
+	}
+
+	if _, err := os.Stat(destPath + "/SUMMARY.md"); err != nil {
+		t.Errorf("Expected SUMMARY.md to be published, got %v", err)
+	}
+}
+
+// copyTree copies a folder, and everything in it
+func copyTree(t *testing.T, from string, to string) {
+	err := filepath.WalkDir(from, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(to, rel), os.ModePerm)
+		}
+		return copyFile(filepath.Dir(path), filepath.Dir(filepath.Join(to, rel)), entry.Name())
+	})
+	if err != nil {
+		t.Fatalf("Error copying %s: %s", from, err)
+	}
+}
+
+// statTree returns the file info of every file in a folder, keyed by its path relative to the folder
+func statTree(t *testing.T, root string) map[string]os.FileInfo {
+	files := make(map[string]os.FileInfo)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = info
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error reading %s: %s", root, err)
+	}
+	return files
+}
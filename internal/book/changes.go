@@ -0,0 +1,142 @@
+package book
+
+import (
+	"github.com/brightercommand/Rewind/internal/config"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"path"
+	"strings"
+)
+
+// Changes What may have changed in the sources since we last published the book, so that we only write again the
+// files that are affected.
+// All means that anything may have changed. Otherwise Root means that the root files may have changed, Summary that a
+// TOC file, or a doc whose front matter may list it, has, so SUMMARY.md must be generated again, and Versions lists the
+// versions whose docs or images may have.
+type Changes struct {
+	All      bool
+	Root     bool
+	Summary  bool
+	Versions map[string]bool
+}
+
+// AllChanges the changes when we know nothing about what has changed, so we write everything
+var AllChanges = Changes{All: true}
+
+// ChangesFor works out which parts of the book are affected by changes to the given files, whose paths are relative to
+// the root of the sources, and use / as a separator. Previous are the sources we last published, or nil if we do not
+// know them.
+// A change to shared affects every version, and a change to a version affects that version and every version that
+// inherits from it. A change to a TOC file, or to a doc, as its front matter may list it in the TOC, also affects
+// SUMMARY.md. A change to rewind.yaml, or to a folder that is not, or is no longer, a version, may change the layout of
+// the whole book, so affects everything. A doc or image that is added, renamed or deleted affects every version, as
+// the links of any version may point to it, with rewind:// or a path into its version's folder.
+func ChangesFor(previous *sources.Sources, s *sources.Sources, paths []string) Changes {
+	cfg := s.Config
+	if cfg == nil {
+		cfg = config.Default()
+	}
+
+	changes := Changes{Versions: make(map[string]bool)}
+	for _, p := range paths {
+		folder, _, nested := strings.Cut(p, "/")
+		inToc := path.Base(p) == cfg.TocFile || path.Ext(p) == ".md"
+
+		switch {
+		case !nested:
+			//a file at the top of the sources is a root file, unless it is our configuration
+			if folder == config.FileName {
+				return AllChanges
+			}
+			changes.Root = true
+		case folder == cfg.RootFolder:
+			changes.Root = true
+		case folder == cfg.SharedFolder:
+			changes.Summary = changes.Summary || inToc
+			for name := range s.Versions {
+				changes.Versions[name] = true
+			}
+		case folder == cfg.SummaryFolder || strings.HasPrefix(folder, "."):
+			//not part of the book
+		default:
+			if _, ok := s.Versions[folder]; !ok {
+				return AllChanges
+			}
+			changes.Summary = changes.Summary || inToc
+			for _, name := range dependants(s, folder) {
+				changes.Versions[name] = true
+			}
+		}
+	}
+
+	if !sameFiles(previous, s) {
+		for name := range s.Versions {
+			changes.Versions[name] = true
+		}
+	}
+	return changes
+}
+
+// sameFiles whether two sources have the same docs and images in shared and in each version; a link can only resolve
+// differently if they do not
+func sameFiles(previous *sources.Sources, s *sources.Sources) bool {
+	if previous == nil || len(previous.Versions) != len(s.Versions) {
+		return false
+	}
+	if !sameKeys(previous.Shared.Docs, s.Shared.Docs) || !sameKeys(previous.Shared.Images, s.Shared.Images) {
+		return false
+	}
+	for name, version := range s.Versions {
+		old, ok := previous.Versions[name]
+		if !ok || !sameKeys(old.Docs, version.Docs) || !sameKeys(old.Images, version.Images) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKeys whether two maps have the same keys
+func sameKeys[V any](a map[string]V, b map[string]V) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// dependants returns a version and every version whose chain includes it
+func dependants(s *sources.Sources, versionName string) []string {
+	var names []string
+	for name := range s.Versions {
+		chain, err := versionChain(s, name)
+		if err != nil {
+			//a broken chain fails the build, which reports it
+			names = append(names, name)
+			continue
+		}
+		for _, version := range chain {
+			if version.Version == versionName {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// affects whether a change may have changed an output
+func (c Changes) affects(out output) bool {
+	switch {
+	case c.All:
+		return true
+	case out.Generated:
+		return c.Summary
+	case out.Version == "":
+		return c.Root
+	default:
+		return c.Versions[out.Version]
+	}
+}
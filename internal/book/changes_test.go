@@ -0,0 +1,144 @@
+package book
+
+import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChangesFor(t *testing.T) {
+	src, _ := findFixture(t, "inheritance")
+
+	tests := []struct {
+		name     string
+		paths    []string
+		expected Changes
+	}{
+		{
+			name:     "shared doc",
+			paths:    []string{"shared/Introduction.md"},
+			expected: Changes{Summary: true, Versions: map[string]bool{"8": true, "9": true, "10": true}},
+		},
+		{
+			name:     "version doc",
+			paths:    []string{"10/Inbox.md"},
+			expected: Changes{Summary: true, Versions: map[string]bool{"10": true}},
+		},
+		{
+			name:     "base version doc",
+			paths:    []string{"9/Outbox.md"},
+			expected: Changes{Summary: true, Versions: map[string]bool{"9": true, "10": true}},
+		},
+		{
+			name:     "version image",
+			paths:    []string{"10/_static/images/Inbox.png"},
+			expected: Changes{Versions: map[string]bool{"10": true}},
+		},
+		{
+			name:     "version TOC",
+			paths:    []string{"10/.toc.yaml"},
+			expected: Changes{Summary: true, Versions: map[string]bool{"10": true}},
+		},
+		{
+			name:     "root file",
+			paths:    []string{"README.md"},
+			expected: Changes{Root: true, Versions: map[string]bool{}},
+		},
+		{
+			name:     "new version",
+			paths:    []string{"11/Inbox.md", "10/Inbox.md"},
+			expected: AllChanges,
+		},
+		{
+			name:     "configuration",
+			paths:    []string{"rewind.yaml"},
+			expected: AllChanges,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := ChangesFor(src, src, test.paths)
+			if !reflect.DeepEqual(changes, test.expected) {
+				t.Errorf("Expected %+v, got %+v", test.expected, changes)
+			}
+		})
+	}
+}
+
+func TestChangesForDocsAddedOrDeleted(t *testing.T) {
+	previous, _ := findFixture(t, "inheritance")
+	src, _ := findFixture(t, "inheritance")
+
+	//a version 10 doc may link to rewind://9/Outbox.md, or ../9/Outbox.md, so deleting it affects every version
+	delete(src.Versions["9"].Docs, "Outbox.md")
+	expected := Changes{Summary: true, Versions: map[string]bool{"8": true, "9": true, "10": true}}
+	changes := ChangesFor(previous, src, []string{"9/Outbox.md"})
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, changes)
+	}
+
+	//so does adding an image, and we cannot tell what was added without the sources we last published
+	expected = Changes{Versions: map[string]bool{"8": true, "9": true, "10": true}}
+	for _, previous := range []*sources.Sources{previous, nil} {
+		src, _ := findFixture(t, "inheritance")
+		src.Versions["10"].Images["New.png"] = pages.Asset{}
+		changes := ChangesFor(previous, src, []string{"10/_static/images/New.png"})
+		if !reflect.DeepEqual(changes, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, changes)
+		}
+	}
+}
+
+func TestPublishChangesOnlyWritesAffectedVersions(t *testing.T) {
+	//we change the sources, so work on a copy of them
+	sourcePath := t.TempDir() + "/inheritance"
+	copyTree(t, fixturePath(t, "inheritance"), sourcePath)
+	destPath := t.TempDir() + "/book"
+
+	publish := func(changes Changes) {
+		src := sources.NewSources()
+		err := src.FindFromPath(sourcePath)
+		if err != nil {
+			t.Fatalf("Error finding sources: %s", err)
+		}
+
+		book, err := MakeBook(context.Background(), src, destPath, Options{NoCache: true})
+		if err != nil {
+			t.Fatalf("Error building book: %s", err)
+		}
+
+		err = book.PublishChanges(context.Background(), changes)
+		if err != nil {
+			t.Fatalf("Error creating book: %s", err)
+		}
+	}
+
+	publish(AllChanges)
+	first := statTree(t, destPath)
+
+	err := os.WriteFile(sourcePath+"/10/Inbox.md", []byte("# Inbox, changed\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+
+	//even without the build cache, we only write the docs of the version that changed
+	publish(Changes{Versions: map[string]bool{"10": true}})
+	second := statTree(t, destPath)
+
+	for path, info := range second {
+		rewritten := strings.HasPrefix(path, "contents/10/") || path == ManifestFileName
+		if rewritten == os.SameFile(first[path], info) {
+			t.Errorf("Expected %s to be rewritten: %t", path, rewritten)
+		}
+	}
+
+	content, err := os.ReadFile(destPath + "/contents/10/Inbox.md")
+	if err != nil || !strings.Contains(string(content), "Inbox, changed") {
+		t.Errorf("Expected contents/10/Inbox.md to be updated, got %q, %v", content, err)
+	}
+}
@@ -0,0 +1,117 @@
+package book
+
+import (
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"regexp"
+)
+
+// conditionPattern matches the condition of a rewind:if directive, such as version >= 10
+var conditionPattern = regexp.MustCompile(`^version\s*(==|!=|>=|<=|>|<)\s*(\S+)$`)
+
+// conditionalBlock A rewind:if block that we are inside
+// Line is the line of the rewind:if, and ElseLine that of its rewind:else, or 0 if we have not seen one. Matched is
+// whether its condition is true for the version, and Outer whether the block it is in keeps its content.
+type conditionalBlock struct {
+	Line     int
+	ElseLine int
+	Matched  bool
+	Outer    bool
+}
+
+// keeps whether we keep the content at this point in the block
+func (c conditionalBlock) keeps() bool {
+	return c.Outer && c.Matched == (c.ElseLine == 0)
+}
+
+// expandConditions keeps only the content of a doc that applies to a version.
+// Content between <!-- rewind:if version >= 10 --> and <!-- rewind:end --> is kept if the condition is true for the
+// version, and the content after an optional <!-- rewind:else --> if it is not. Blocks may be nested. We compare
+// versions as we order them in the TOC, except that versions such as 9 and 9.0.0 are equal.
+// It returns the lines that we keep, with their line in the doc, or a DirectiveError for a malformed condition, or a
+// block that is not closed, or not opened.
+func expandConditions(path string, content []byte, version string) ([]docLine, error) {
+	lines := docLines(content)
+	if !hasDirectives(content) {
+		return lines, nil
+	}
+
+	var kept []docLine
+	var blocks []conditionalBlock
+	keeping := func() bool {
+		return len(blocks) == 0 || blocks[len(blocks)-1].keeps()
+	}
+	fail := func(line int, format string, args ...interface{}) error {
+		return &DirectiveError{Path: path, Line: line, Version: version, Message: fmt.Sprintf(format, args...)}
+	}
+
+	for _, line := range lines {
+		switch line.Name {
+		case "if":
+			matched, err := evaluateCondition(version, line.Args)
+			if err != nil {
+				return nil, fail(line.Number, "%s", err)
+			}
+			blocks = append(blocks, conditionalBlock{Line: line.Number, Matched: matched, Outer: keeping()})
+		case "else":
+			if len(blocks) == 0 {
+				return nil, fail(line.Number, "rewind:else without a rewind:if")
+			}
+			if line.Args != "" {
+				return nil, fail(line.Number, "rewind:else takes no condition, got %q", line.Args)
+			}
+			block := &blocks[len(blocks)-1]
+			if block.ElseLine != 0 {
+				return nil, fail(line.Number, "second rewind:else for the rewind:if on line %d", block.Line)
+			}
+			block.ElseLine = line.Number
+		case "end":
+			if len(blocks) == 0 {
+				return nil, fail(line.Number, "rewind:end without a rewind:if")
+			}
+			if line.Args != "" {
+				return nil, fail(line.Number, "rewind:end takes no condition, got %q", line.Args)
+			}
+			blocks = blocks[:len(blocks)-1]
+		default:
+			if keeping() {
+				kept = append(kept, line)
+			}
+		}
+	}
+
+	if len(blocks) > 0 {
+		return nil, fail(blocks[len(blocks)-1].Line, "rewind:if without a rewind:end")
+	}
+	return kept, nil
+}
+
+// evaluateCondition returns whether the condition of a rewind:if, such as version >= 10, is true for a version
+func evaluateCondition(version string, condition string) (bool, error) {
+	match := conditionPattern.FindStringSubmatch(condition)
+	if match == nil {
+		return false, fmt.Errorf("malformed condition %q, expected version, then one of == != < <= > >=, then a version", condition)
+	}
+
+	current, operand := pages.ParseVersion(version), pages.ParseVersion(match[2])
+	//versions that are the same, such as 9 and 9.0.0, are equal here, rather than ordered by name as in the TOC
+	if current.Valid && operand.Valid {
+		operand.Name = current.Name
+	}
+
+	c := pages.CompareVersions(current, operand)
+	switch match[1] {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
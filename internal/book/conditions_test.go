@@ -0,0 +1,136 @@
+package book
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpandConditions(t *testing.T) {
+	doc := "# Outbox\n" +
+		"<!-- rewind:if version >= 10 -->\n" +
+		"Use the new outbox.\n" +
+		"<!-- rewind:if version == 10.0.0 -->\n" +
+		"New in 10.\n" +
+		"<!-- rewind:end -->\n" +
+		"<!-- rewind:else -->\n" +
+		"Use the old outbox.\n" +
+		"<!-- rewind:end -->\n" +
+		"```html\n" +
+		"<!-- rewind:if version >= 10 -->\n" +
+		"```\n" +
+		"Done.\n"
+
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{
+			version: "9",
+			expected: "# Outbox\n" +
+				"Use the old outbox.\n" +
+				"```html\n<!-- rewind:if version >= 10 -->\n```\n" +
+				"Done.\n",
+		},
+		{
+			version: "10",
+			expected: "# Outbox\n" +
+				"Use the new outbox.\n" +
+				"New in 10.\n" +
+				"```html\n<!-- rewind:if version >= 10 -->\n```\n" +
+				"Done.\n",
+		},
+		{
+			version: "v11.2",
+			expected: "# Outbox\n" +
+				"Use the new outbox.\n" +
+				"```html\n<!-- rewind:if version >= 10 -->\n```\n" +
+				"Done.\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			lines, err := expandConditions("Outbox.md", []byte(doc), test.version)
+			if err != nil {
+				t.Fatalf("Error expanding conditions: %s", err)
+			}
+			var expanded string
+			for _, line := range lines {
+				expanded += string(line.Text)
+			}
+			if expanded != test.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", test.expected, expanded)
+			}
+		})
+	}
+}
+
+func TestExpandConditionsReportsMalformedBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name:     "unclosed",
+			doc:      "# Outbox\n<!-- rewind:if version >= 10 -->\nNew\n",
+			expected: "Outbox.md:2: version 9: rewind:if without a rewind:end",
+		},
+		{
+			name:     "end without if",
+			doc:      "# Outbox\n\n<!-- rewind:end -->\n",
+			expected: "Outbox.md:3: version 9: rewind:end without a rewind:if",
+		},
+		{
+			name:     "else without if",
+			doc:      "<!-- rewind:else -->\n",
+			expected: "Outbox.md:1: version 9: rewind:else without a rewind:if",
+		},
+		{
+			name:     "second else",
+			doc:      "<!-- rewind:if version > 8 -->\n<!-- rewind:else -->\n<!-- rewind:else -->\n<!-- rewind:end -->\n",
+			expected: "Outbox.md:3: version 9: second rewind:else for the rewind:if on line 1",
+		},
+		{
+			name: "malformed condition",
+			doc:  "<!-- rewind:if release >= 10 -->\n<!-- rewind:end -->\n",
+			expected: `Outbox.md:1: version 9: malformed condition "release >= 10", expected version, then one of == != < <= > >=, ` +
+				"then a version",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := expandConditions("Outbox.md", []byte(test.doc), "9")
+			var directiveErr *DirectiveError
+			if !errors.As(err, &directiveErr) {
+				t.Fatalf("Expected a DirectiveError, got %v", err)
+			}
+			if err.Error() != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestUnknownDirective(t *testing.T) {
+	err := checkDirectives("Outbox.md", []byte("# Outbox\n<!-- rewind:unless version >= 10 -->\n"), "9")
+	if err == nil || err.Error() != "Outbox.md:2: version 9: unknown directive rewind:unless" {
+		t.Errorf("Expected an unknown directive, got %v", err)
+	}
+}
+
+func TestErrorLinesAfterExpandingDirectives(t *testing.T) {
+	src, sourcePath := findFixture(t, "errorlines")
+
+	//each doc drops a block of lines for version 9 before the error, and a link in a partial is reported on its line of
+	//the partial
+	expected := sourcePath + "/shared/Links.md:8: version 9: link Missing.md does not resolve to a doc or image of the version\n" +
+		sourcePath + "/shared/partials/part.md:3: version 9: link Gone.md does not resolve to a doc or image of the version\n" +
+		sourcePath + `/shared/Vars.md:8: version 9: executing "Vars.md" at <.Vars.Missing>: map has no entry for key "Missing"`
+
+	problems := Validate(src)
+	if problems.Error() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, problems.Error())
+	}
+}
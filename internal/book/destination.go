@@ -0,0 +1,142 @@
+package book
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// gitFolderName is the folder of a git repository, which we expect to find in the destination and never touch
+const gitFolderName = ".git"
+
+// maxForeignFiles is how many of the files we did not write we list when we refuse to publish over them
+const maxForeignFiles = 5
+
+// checkDestination checks that we can publish the book without harming anything.
+// We refuse to publish into the sources, or to a destination that holds the sources, as we would overwrite or prune
+// our own sources. Unless Options.Force is set, we also refuse to publish to a destination that holds files that we
+// did not write, according to its manifest, as it is probably not a book. We ignore a .git folder, as the book is often
+// a repository of its own.
+func (b *Book) checkDestination() error {
+	destPath, err := resolvePath(b.Root.DestPath)
+	if err != nil {
+		return err
+	}
+
+	if b.Root.SourcePath != "" {
+		sourcePath, err := resolvePath(b.Root.SourcePath)
+		if err != nil {
+			return err
+		}
+
+		if within(destPath, sourcePath) {
+			return &UnsafeDestinationError{Path: b.Root.DestPath, Reason: "the destination is inside the sources at " + b.Root.SourcePath}
+		}
+		if within(sourcePath, destPath) {
+			return &UnsafeDestinationError{Path: b.Root.DestPath, Reason: "the sources at " + b.Root.SourcePath + " are inside the destination"}
+		}
+	}
+
+	if b.Options.Force {
+		return nil
+	}
+
+	foreign, err := b.foreignFiles()
+	if err != nil {
+		return err
+	}
+	if len(foreign) > 0 {
+		reason := "the destination holds files that Rewind did not write, use --force to publish anyway"
+		if len(foreign) > maxForeignFiles {
+			foreign = append(foreign[:maxForeignFiles], "...")
+		}
+		return &UnsafeDestinationError{Path: b.Root.DestPath, Reason: reason, Files: foreign}
+	}
+	return nil
+}
+
+// foreignFiles returns the files in the destination that are not in its manifest, other than the manifest itself and
+// anything in a .git folder, relative to the root of the book and in order
+func (b *Book) foreignFiles() ([]string, error) {
+	if _, err := os.Stat(b.Root.DestPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	manifest, err := readManifest(b.Root.DestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	written := map[string]bool{ManifestFileName: true}
+	for _, entry := range manifest.Files {
+		written[entry.Path] = true
+	}
+
+	var foreign []string
+	err = filepath.WalkDir(b.Root.DestPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if entry.Name() == gitFolderName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(b.Root.DestPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !written[rel] {
+			foreign = append(foreign, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(foreign)
+	return foreign, nil
+}
+
+// resolvePath returns the absolute path of a file, with any symbolic links in it resolved.
+// The file need not exist; we resolve as much of the path as does.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return filepath.Join(append([]string{abs}, missing...)...), nil
+		}
+		missing = append([]string{filepath.Base(abs)}, missing...)
+		abs = parent
+	}
+}
+
+// within returns true if path is folder, or is inside it; both must be absolute and clean
+func within(path string, folder string) bool {
+	rel, err := filepath.Rel(folder, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,56 @@
+package book
+
+import (
+	"errors"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckDestination(t *testing.T) {
+	sourcePath := t.TempDir()
+	destParent := t.TempDir()
+
+	tests := []struct {
+		name     string
+		destPath string
+		source   string
+		files    []string
+		force    bool
+		unsafe   bool
+	}{
+		{name: "new destination", destPath: destParent + "/book", source: sourcePath},
+		{name: "inside the sources", destPath: sourcePath + "/book", source: sourcePath, unsafe: true},
+		{name: "the sources", destPath: sourcePath, source: sourcePath, unsafe: true},
+		{name: "holds the sources", destPath: destParent, source: destParent + "/source", unsafe: true},
+		{name: "git repository", destPath: destParent + "/git", source: sourcePath, files: []string{".git/HEAD"}},
+		{name: "foreign files", destPath: destParent + "/foreign", source: sourcePath, files: []string{"notes.txt"}, unsafe: true},
+		{name: "foreign files forced", destPath: destParent + "/forced", source: sourcePath, files: []string{"notes.txt"}, force: true},
+		{name: "published before", destPath: destParent + "/published", source: sourcePath, files: []string{ManifestFileName}},
+	}
+
+	for _, test := range tests {
+		for _, file := range test.files {
+			err := os.MkdirAll(filepath.Dir(test.destPath+"/"+file), os.ModePerm)
+			if err != nil {
+				t.Fatalf("Error making folder: %s", err)
+			}
+			err = os.WriteFile(test.destPath+"/"+file, []byte("files: []\n"), 0644)
+			if err != nil {
+				t.Fatalf("Error writing file: %s", err)
+			}
+		}
+
+		b := &Book{
+			Root:    &pages.Root{SourcePath: test.source, DestPath: test.destPath},
+			Options: Options{Force: test.force},
+		}
+
+		err := b.checkDestination()
+		var unsafe *UnsafeDestinationError
+		if errors.As(err, &unsafe) != test.unsafe {
+			t.Errorf("%s: expected unsafe to be %t, got %v", test.name, test.unsafe, err)
+		}
+	}
+}
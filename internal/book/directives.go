@@ -0,0 +1,105 @@
+package book
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// directivePrefix is how every directive to Rewind in a doc starts
+const directivePrefix = "rewind:"
+
+// directivePattern matches a line that holds only a directive, an HTML comment such as <!-- rewind:if version >= 10 -->
+var directivePattern = regexp.MustCompile(`^\s*<!--\s*rewind:([a-z]+)(?:\s+(.*?))?\s*-->\s*$`)
+
+// knownDirectives the names of the directives that we expand
+var knownDirectives = map[string]bool{"if": true, "else": true, "end": true, "include": true, "snippet": true}
+
+// docLine A line of a doc, with its line ending, and the directive on it, if it is one
+// Number counts from 1. Name and Args are the name of the directive, e.g. if, and what follows it, e.g. version >= 10;
+// Name is empty if the line is not a directive.
+type docLine struct {
+	Text   []byte
+	Number int
+	Name   string
+	Args   string
+}
+
+// sourceLine Where a line of a doc that we have expanded came from: a line of the doc, or of a partial it includes
+type sourceLine struct {
+	Path string
+	Line int
+}
+
+// lineMap The source of each line of an expanded doc, so that we can report an error on the line that caused it.
+// The source of line n of the expanded doc is at n-1.
+type lineMap []sourceLine
+
+// locate returns the source of a line of an expanded doc. If we do not know it, we return the path of the doc and a
+// line of 0, so that we report the doc without a line, rather than the wrong line.
+func (m lineMap) locate(path string, line int) (string, int) {
+	if line < 1 || line > len(m) {
+		return path, 0
+	}
+	return m[line-1].Path, m[line-1].Line
+}
+
+// lineCount the number of lines in a doc, counting a last line without a line ending
+func lineCount(content []byte) int {
+	count := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		count++
+	}
+	return count
+}
+
+// docLines splits a doc into lines and finds the directives in it.
+// A line in a fenced code block is never a directive, so that a doc can show one.
+func docLines(content []byte) []docLine {
+	var lines []docLine
+	var fence string
+	for i, text := range bytes.SplitAfter(content, []byte("\n")) {
+		if len(text) == 0 {
+			continue
+		}
+		line := docLine{Text: text, Number: i + 1}
+
+		trimmed := strings.TrimSpace(string(text))
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			for len(fence) < len(trimmed) && trimmed[len(fence)] == fence[0] {
+				fence += fence[:1]
+			}
+		default:
+			if match := directivePattern.FindStringSubmatch(trimmed); match != nil {
+				line.Name, line.Args = match[1], match[2]
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// hasDirectives whether a doc may hold a directive, so that we only look at docs that do
+func hasDirectives(content []byte) bool {
+	return bytes.Contains(content, []byte(directivePrefix))
+}
+
+// checkDirectives returns a DirectiveError for the first directive in a doc that is not one we know
+func checkDirectives(path string, content []byte, version string) error {
+	if !hasDirectives(content) {
+		return nil
+	}
+
+	for _, line := range docLines(content) {
+		if line.Name != "" && !knownDirectives[line.Name] {
+			return &DirectiveError{Path: path, Line: line.Number, Version: version, Message: "unknown directive rewind:" + line.Name}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package book
+
+import (
+	"github.com/brightercommand/Rewind/internal/work"
+	"strconv"
+	"strings"
+)
+
+// TocParseError A .toc.yaml file that could not be read or parsed
+// Line is the line the parser failed on, or zero if we don't know it.
+type TocParseError struct {
+	Version string
+	Path    string
+	Line    int
+	Err     error
+}
+
+func (e *TocParseError) Error() string {
+	return describe(e.Path, e.Line, e.Version, e.Err.Error())
+}
+
+func (e *TocParseError) Unwrap() error {
+	return e.Err
+}
+
+// MissingTocError A version, or shared, without a .toc.yaml file
+// File is the name of the TOC file we looked for, as the layout of the sources may name it something else.
+type MissingTocError struct {
+	Version string
+	Path    string
+	File    string
+}
+
+func (e *MissingTocError) Error() string {
+	return describe(e.Path, 0, e.Version, "no "+e.File+" found")
+}
+
+// MissingDocError A TOC entry that points to a file that is not a doc in the version, or that has no file at all
+type MissingDocError struct {
+	Version string
+	Section string
+	Entry   string
+	File    string
+}
+
+func (e *MissingDocError) Error() string {
+	if e.File == "" {
+		return describe("", 0, e.Version, "entry "+e.Entry+" in section "+e.Section+" has no file")
+	}
+	return describe("", 0, e.Version, "entry "+e.Entry+" in section "+e.Section+" points to "+e.File+
+		", which is not a doc in this version")
+}
+
+// OrphanDocError A doc in a version that no TOC entry points to
+type OrphanDocError struct {
+	Version string
+	Path    string
+	File    string
+}
+
+func (e *OrphanDocError) Error() string {
+	return describe(e.Path, 0, e.Version, e.File+" is not in the TOC")
+}
+
+// DuplicateEntryError Two TOC entries in a version with the same name, or that point to the same file
+// If File is empty the entries have the same name, otherwise they point to the same file.
+type DuplicateEntryError struct {
+	Version      string
+	Section      string
+	Entry        string
+	OtherSection string
+	OtherEntry   string
+	File         string
+}
+
+func (e *DuplicateEntryError) Error() string {
+	if e.File == "" {
+		return describe("", 0, e.Version, "entry "+e.Entry+" in section "+e.Section+
+			" has the same name as an entry in section "+e.OtherSection)
+	}
+	return describe("", 0, e.Version, "entry "+e.Entry+" in section "+e.Section+" points to "+e.File+
+		", which entry "+e.OtherEntry+" also points to")
+}
+
+// VersionError Any other error that happened while making a version
+type VersionError struct {
+	Version string
+	Err     error
+}
+
+func (e *VersionError) Error() string {
+	return describe("", 0, e.Version, e.Err.Error())
+}
+
+func (e *VersionError) Unwrap() error {
+	return e.Err
+}
+
+// UnsafeDestinationError A destination we refuse to publish to
+// Files lists some of the files in the destination that we did not write, if that is why we refuse.
+type UnsafeDestinationError struct {
+	Path   string
+	Reason string
+	Files  []string
+}
+
+func (e *UnsafeDestinationError) Error() string {
+	message := e.Reason
+	if len(e.Files) > 0 {
+		message += ": " + strings.Join(e.Files, ", ")
+	}
+	return describe(e.Path, 0, "", message)
+}
+
+// BrokenLinkError A link or image in a doc that does not resolve to a file that we publish in the version
+type BrokenLinkError struct {
+	Path    string
+	Line    int
+	Version string
+	Link    string
+}
+
+func (e *BrokenLinkError) Error() string {
+	return describe(e.Path, e.Line, e.Version, "link "+e.Link+" does not resolve to a doc or image of the version")
+}
+
+// VersionLinkError A link to a version, such as rewind://9/Outbox.md, that names a version, or a doc of a version,
+// that we do not publish
+type VersionLinkError struct {
+	Path    string
+	Line    int
+	Version string
+	Link    string
+	Err     error
+}
+
+func (e *VersionLinkError) Error() string {
+	return describe(e.Path, e.Line, e.Version, "link "+e.Link+": "+e.Err.Error())
+}
+
+func (e *VersionLinkError) Unwrap() error {
+	return e.Err
+}
+
+// TemplateError A doc whose template cannot be parsed, or uses a variable that its version does not have
+type TemplateError struct {
+	Path    string
+	Line    int
+	Version string
+	Message string
+	Err     error
+}
+
+func (e *TemplateError) Error() string {
+	return describe(e.Path, e.Line, e.Version, e.Message)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// DirectiveError A directive to Rewind in a doc, such as <!-- rewind:if version >= 10 -->, that is malformed, or that
+// we cannot follow
+type DirectiveError struct {
+	Path    string
+	Line    int
+	Version string
+	Message string
+}
+
+func (e *DirectiveError) Error() string {
+	return describe(e.Path, e.Line, e.Version, e.Message)
+}
+
+// FrontMatterError The front matter of a doc with one of our keys, such as order, that has a value of the wrong type
+// Line is the line of the doc the parser failed on, or zero if we don't know it.
+type FrontMatterError struct {
+	Path    string
+	Line    int
+	Version string
+	Err     error
+}
+
+func (e *FrontMatterError) Error() string {
+	return describe(e.Path, e.Line, e.Version, "front matter: "+e.Err.Error())
+}
+
+func (e *FrontMatterError) Unwrap() error {
+	return e.Err
+}
+
+// Errors The errors from every version, so that one run reports every failure
+type Errors = work.Errors
+
+// describe formats an error as path:line: version v: message, leaving out anything we don't know
+func describe(path string, line int, version string, message string) string {
+	var description strings.Builder
+	if path != "" {
+		description.WriteString(path)
+		if line > 0 {
+			description.WriteString(":" + strconv.Itoa(line))
+		}
+		description.WriteString(": ")
+	}
+	if version != "" {
+		description.WriteString("version " + version + ": ")
+	}
+	description.WriteString(message)
+	return description.String()
+}
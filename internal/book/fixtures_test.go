@@ -0,0 +1,51 @@
+package book
+
+import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fixturePath the path of a fixture in the test folder
+func fixturePath(t *testing.T, name string) string {
+	t.Helper()
+	myDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Error getting working directory: %s", err)
+	}
+	return strings.Replace(myDir, "internal/book", "test/"+name, 1)
+}
+
+// findFixture finds the sources of a fixture in the test folder, and returns them with the fixture's path
+func findFixture(t *testing.T, name string) (*sources.Sources, string) {
+	t.Helper()
+	sourcePath := fixturePath(t, name)
+
+	src := sources.NewSources()
+	err := src.FindFromPath(sourcePath)
+	if err != nil {
+		t.Fatalf("Error finding sources: %s", err)
+	}
+	return src, sourcePath
+}
+
+// publishFixture publishes the book of a fixture in the test folder to a temporary folder, which the test removes
+// when it ends, and returns the path of the book
+func publishFixture(t *testing.T, name string) (destPath string) {
+	t.Helper()
+	src, _ := findFixture(t, name)
+	destPath = t.TempDir() + "/book"
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{NoCache: true})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Error creating book: %s", err)
+	}
+	return destPath
+}
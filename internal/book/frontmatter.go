@@ -0,0 +1,201 @@
+package book
+
+import (
+	"bytes"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"gopkg.in/yaml.v3"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// frontMatterFence the line that opens and closes the front matter at the top of a doc
+const frontMatterFence = "---"
+
+// frontMatter What the front matter of a doc tells us about its entry in the TOC
+// Section is the section of the TOC that lists the doc; we do not list a doc without one. Title is the name of its
+// entry, or the doc's file name without its extension if empty, and Order orders the entry within the section. Hidden
+// keeps the doc out of the TOC, though we still publish it.
+type frontMatter struct {
+	Section string `yaml:"section"`
+	Title   string `yaml:"title"`
+	Order   int    `yaml:"order"`
+	Hidden  bool   `yaml:"hidden"`
+}
+
+// rewindFrontMatter the keys of front matter that are ours; we strip these from the docs we publish, and keep any
+// others, such as GitBook's description, for the tools that read them
+var rewindFrontMatter = map[string]bool{"section": true, "title": true, "order": true, "hidden": true}
+
+// splitFrontMatter splits the YAML front matter, between --- lines at the top of a doc, from the rest of the doc.
+// The block between the fences is only front matter if it is a YAML mapping; otherwise the --- lines are thematic
+// breaks, and the doc has no front matter.
+// It returns the front matter as a mapping, the doc after the closing fence, and whether the doc has front matter.
+func splitFrontMatter(content []byte) (matter *yaml.Node, body []byte, ok bool) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if !isFrontMatterFence(lines[0]) {
+		return nil, content, false
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if !isFrontMatterFence(lines[i]) {
+			continue
+		}
+
+		var document yaml.Node
+		err := yaml.Unmarshal(bytes.Join(lines[1:i], nil), &document)
+		if err != nil || len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+			return nil, content, false
+		}
+		return document.Content[0], bytes.Join(lines[i+1:], nil), true
+	}
+	return nil, content, false
+}
+
+// isFrontMatterFence whether a line opens or closes front matter
+func isFrontMatterFence(line []byte) bool {
+	return strings.TrimRight(string(line), " \t\r\n") == frontMatterFence
+}
+
+// stripFrontMatter removes our keys from the front matter of a doc that we publish.
+// We write back any other keys as front matter; if there are none, we remove the front matter, and the blank lines
+// after it. A doc without any of our keys is returned as it is.
+func stripFrontMatter(content []byte) ([]byte, error) {
+	matter, body, ok := splitFrontMatter(content)
+	if !ok {
+		return content, nil
+	}
+
+	//a mapping holds each key, followed by its value
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(matter.Content); i += 2 {
+		if !rewindFrontMatter[matter.Content[i].Value] {
+			kept = append(kept, matter.Content[i], matter.Content[i+1])
+		}
+	}
+
+	switch {
+	case len(kept) == len(matter.Content):
+		return content, nil
+	case len(kept) == 0:
+		return bytes.TrimLeft(body, "\r\n"), nil
+	}
+
+	matter.Content = kept
+	var stripped bytes.Buffer
+	stripped.WriteString(frontMatterFence + "\n")
+	encoder := yaml.NewEncoder(&stripped)
+	encoder.SetIndent(2)
+	err := encoder.Encode(matter)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	stripped.WriteString(frontMatterFence + "\n")
+	stripped.Write(body)
+	return stripped.Bytes(), nil
+}
+
+// readFrontMatter reads the front matter of a doc, or returns nil if it has none
+// It returns a FrontMatterError if one of our keys has a value of the wrong type, with the line of the doc that failed
+// if the parser tells us.
+func readFrontMatter(version string, doc pages.Doc) (*frontMatter, error) {
+	path := doc.SourcePath + "/" + doc.Storage.Name()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	matter, _, ok := splitFrontMatter(content)
+	if !ok {
+		return nil, nil
+	}
+
+	var fm frontMatter
+	err = matter.Decode(&fm)
+	if err != nil {
+		matterErr := &FrontMatterError{Path: path, Version: version, Err: err}
+		if match := yamlLine.FindStringSubmatch(err.Error()); match != nil {
+			//the front matter starts on the line after the fence
+			line, _ := strconv.Atoi(match[1])
+			matterErr.Line = line + 1
+		}
+		return nil, matterErr
+	}
+	return &fm, nil
+}
+
+// addFrontMatterEntries adds an entry to the merged TOC of a version for each doc whose front matter names a section.
+// An entry in a .toc.yaml file wins, so we only look at the docs that no entry lists, and that are not the landing
+// page. A section that only front matter names is ordered after those of the TOC files. The docs whose front matter
+// hides them are held in the TOC's Hidden.
+// It returns the errors from any front matter we cannot parse.
+func (b *Book) addFrontMatterEntries(versionName string, toc *pages.Toc) error {
+	version, ok := b.Versions[versionName]
+	if !ok {
+		return nil
+	}
+
+	log.Print("Adding front matter entries for version " + versionName + "...")
+	listed := map[string]bool{toc.LandingPage: true}
+	lastOrder := 0
+	for _, section := range toc.Sections {
+		for _, file := range entryFiles(section.Entries) {
+			listed[file] = true
+		}
+		if section.Order > lastOrder {
+			lastOrder = section.Order
+		}
+	}
+
+	var errs Errors
+	var newSections []string
+	for _, docName := range sortedKeys(version.Docs) {
+		if listed[docName] {
+			continue
+		}
+
+		matter, err := readFrontMatter(versionName, version.Docs[docName])
+		if err != nil {
+			errs.Add(err)
+			continue
+		}
+
+		if matter == nil {
+			continue
+		}
+		if matter.Hidden {
+			toc.Hidden = append(toc.Hidden, docName)
+			continue
+		}
+		if matter.Section == "" {
+			continue
+		}
+
+		section, ok := toc.Sections[matter.Section]
+		if !ok {
+			section = &pages.TOCSection{}
+			toc.Sections[matter.Section] = section
+			newSections = append(newSections, matter.Section)
+		}
+
+		title := matter.Title
+		if title == "" {
+			title = strings.TrimSuffix(docName, filepath.Ext(docName))
+		}
+		section.Entries = append(section.Entries, pages.TOCEntry{Name: title, File: docName, Order: matter.Order})
+	}
+
+	sort.Strings(newSections)
+	for i, name := range newSections {
+		toc.Sections[name].Order = lastOrder + i + 1
+	}
+
+	return errs.OrNil()
+}
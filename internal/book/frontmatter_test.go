@@ -0,0 +1,136 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFrontMatterEntries(t *testing.T) {
+	src, _ := findFixture(t, "frontmatter")
+	destPath := t.TempDir() + "/book"
+
+	//the hidden doc is not an orphan
+	problems := Validate(src)
+	if len(problems) > 0 {
+		t.Errorf("Expected no problems, got %s", problems)
+	}
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{NoCache: true})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	//the entry in .toc.yaml wins over the front matter of Introduction.md, the title of Inbox.md is its file name, and
+	//version 10 overrides the front matter of Outbox.md with that of its own Outbox.md
+	expected := "## 9\n\n" +
+		"### Overview\n\n" +
+		" * [Introduction](/contents/9/Introduction.md)\n" +
+		" * [The Outbox](/contents/9/Outbox.md)\n\n" +
+		"### Guarantees\n\n" +
+		" * [Inbox](/contents/9/Inbox.md)\n\n" +
+		"## 10\n\n" +
+		"### Overview\n\n" +
+		" * [The Outbox in 10](/contents/10/Outbox.md)\n" +
+		" * [Introduction](/contents/10/Introduction.md)\n\n" +
+		"### Guarantees\n\n" +
+		" * [Inbox](/contents/10/Inbox.md)\n"
+	if !strings.HasPrefix(string(book.Root.Summary), expected) {
+		t.Errorf("Expected the summary to be\n%s\ngot\n%s", expected, book.Root.Summary)
+	}
+
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Error creating book: %s", err)
+	}
+
+	//we strip our keys from the front matter, but keep GitBook's, and still publish the hidden doc
+	published := map[string]string{
+		"contents/9/Introduction.md": "# Introduction\n",
+		"contents/9/Outbox.md":       "---\ndescription: Dispatch messages reliably\n---\n# Outbox\n",
+		"contents/9/Draft.md":        "# Draft\n",
+		"contents/10/Outbox.md":      "# Outbox in 10\n",
+	}
+	for path, want := range published {
+		content, err := os.ReadFile(destPath + "/" + path)
+		if err != nil {
+			t.Errorf("Error reading %s: %s", path, err)
+			continue
+		}
+		if string(content) != want {
+			t.Errorf("Expected %s to be %q, got %q", path, want, content)
+		}
+	}
+}
+
+func TestMalformedFrontMatter(t *testing.T) {
+	src, sourcePath := findFixture(t, "badfrontmatter")
+
+	_, err := MakeBook(context.Background(), src, "", Options{NoCache: true})
+	var matterErr *FrontMatterError
+	if !errors.As(err, &matterErr) {
+		t.Fatalf("Expected a FrontMatterError, got %v", err)
+	}
+	if matterErr.Path != sourcePath+"/shared/Doc.md" || matterErr.Line != 3 || matterErr.Version != "9" {
+		t.Errorf("Expected the error on line 3 of shared/Doc.md in version 9, got %s", matterErr)
+	}
+}
+
+func TestStripFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name:     "front matter",
+			doc:      "---\r\ntitle: Outbox\r\nsection: Overview\r\n---\r\n\r\n# Outbox\r\n",
+			expected: "# Outbox\r\n",
+		},
+		{
+			name: "gitbook keys",
+			doc: "---\ndescription: Dispatch messages reliably\nsection: Overview\nlayout:\n  title:\n    visible: false\n" +
+				"order: 10\n---\n\n# Outbox\n",
+			expected: "---\ndescription: Dispatch messages reliably\nlayout:\n  title:\n    visible: false\n---\n\n# Outbox\n",
+		},
+		{
+			name:     "only gitbook keys",
+			doc:      "---\ndescription: Dispatch messages reliably\n---\n# Outbox\n",
+			expected: "---\ndescription: Dispatch messages reliably\n---\n# Outbox\n",
+		},
+		{
+			name:     "thematic breaks",
+			doc:      "---\nIntro paragraph, between two breaks.\n---\n# Rule\n",
+			expected: "---\nIntro paragraph, between two breaks.\n---\n# Rule\n",
+		},
+		{
+			name:     "not yaml",
+			doc:      "---\nsection: [Overview\n---\n# Rule\n",
+			expected: "---\nsection: [Overview\n---\n# Rule\n",
+		},
+		{
+			name:     "not closed",
+			doc:      "---\ntitle: Outbox\n# Outbox\n",
+			expected: "---\ntitle: Outbox\n# Outbox\n",
+		},
+		{
+			name:     "no front matter",
+			doc:      "# Outbox\n\n---\n\nMore\n",
+			expected: "# Outbox\n\n---\n\nMore\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stripped, err := stripFrontMatter([]byte(test.doc))
+			if err != nil {
+				t.Fatalf("Error stripping front matter: %s", err)
+			}
+			if string(stripped) != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, stripped)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package book
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"os"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden SUMMARY.md files in test/golden")
+
+// TestGoldenSummaries builds the SUMMARY.md for each of our test source trees and checks that it is byte for byte the
+// same on every run, and the same as the golden file for that tree.
+// Run with -update to regenerate the golden files after an intended change to the output.
+func TestGoldenSummaries(t *testing.T) {
+	myDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Error getting working directory: %s", err)
+	}
+
+	tests := []struct {
+		golden  string
+		source  string
+		options Options
+	}{
+		{golden: "source", source: "source"},
+		{golden: "source-descending", source: "source", options: Options{VersionOrder: pages.Descending}},
+		{golden: "inheritance", source: "inheritance"},
+		{golden: "tombstones", source: "tombstones"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			sourcePath := strings.Replace(myDir, "internal/book", "test/"+test.source, 1)
+			goldenPath := strings.Replace(myDir, "internal/book", "test/golden/"+test.golden+"/"+pages.SummaryFileName, 1)
+
+			first := buildSummary(t, sourcePath, test.options)
+			for i := 0; i < 5; i++ {
+				next := buildSummary(t, sourcePath, test.options)
+				if !bytes.Equal(first, next) {
+					t.Fatalf("Expected identical summaries on every run, got\n%s\nthen\n%s", first, next)
+				}
+			}
+
+			if *update {
+				err := os.WriteFile(goldenPath, first, 0644)
+				if err != nil {
+					t.Fatalf("Error updating golden file: %s", err)
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Error reading golden file: %s", err)
+			}
+
+			if !bytes.Equal(first, golden) {
+				t.Errorf("Expected the golden file %s\n%s\ngot\n%s", goldenPath, golden, first)
+			}
+		})
+	}
+}
+
+func buildSummary(t *testing.T, sourcePath string, options Options) []byte {
+	src := sources.NewSources()
+	err := src.FindFromPath(sourcePath)
+	if err != nil {
+		t.Fatalf("Error finding sources: %s", err)
+	}
+
+	book, err := MakeBook(context.Background(), src, sourcePath+"/../docs", options)
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+	return book.Root.Summary
+}
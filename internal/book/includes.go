@@ -0,0 +1,94 @@
+package book
+
+import (
+	"bytes"
+	"os"
+	"strings"
+)
+
+// expandDirectives expands the directives in a doc, or a partial, that we publish in a version.
+// We check that we know every directive, keep the content whose conditions hold for the version, then replace each
+// <!-- rewind:include partials/<path> --> with the partial, itself expanded in the same way, and each
+// <!-- rewind:snippet samples/<path>#<region> --> with the snippet of code; see readSnippet. A partial, or a sample, is
+// resolved as a doc is: the version's own, or else that of the nearest version it inherits from, or else shared's.
+// Including is the chain of partials we are inside, to find a partial that includes itself.
+// It returns the expanded doc, and the source of each of its lines, or a DirectiveError if a partial or snippet does not
+// exist, or a partial includes itself.
+func (b *Book) expandDirectives(version string, path string, content []byte, including []string) ([]byte, lineMap, error) {
+	err := checkDirectives(path, content, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines, err := expandConditions(path, content, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var expanded bytes.Buffer
+	var sources lineMap
+	for _, line := range lines {
+		switch line.Name {
+		case "include":
+			partial, partialSources, err := b.readPartial(version, path, line, including)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			expanded.Write(partial)
+			if len(partial) > 0 && !bytes.HasSuffix(partial, []byte("\n")) {
+				expanded.WriteString("\n")
+			}
+			sources = append(sources, partialSources...)
+		case "snippet":
+			snippet, err := b.readSnippet(version, path, line)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			expanded.Write(snippet)
+			//every line of a snippet comes from the directive
+			for i := 0; i < lineCount(snippet); i++ {
+				sources = append(sources, sourceLine{Path: path, Line: line.Number})
+			}
+		default:
+			expanded.Write(line.Text)
+			sources = append(sources, sourceLine{Path: path, Line: line.Number})
+		}
+	}
+	return expanded.Bytes(), sources, nil
+}
+
+// readPartial reads and expands the partial that an include directive on a line of a doc names
+// It returns the expanded partial, and the source of each of its lines.
+func (b *Book) readPartial(version string, path string, line docLine, including []string) ([]byte, lineMap, error) {
+	fail := func(message string) error {
+		return &DirectiveError{Path: path, Line: line.Number, Version: version, Message: message}
+	}
+
+	prefix := b.Config.PartialsFolder + "/"
+	if !strings.HasPrefix(line.Args, prefix) {
+		return nil, nil, fail("rewind:include must name a partial in " + prefix + ", got " + line.Args)
+	}
+	key := strings.TrimPrefix(line.Args, prefix)
+
+	for i, included := range including {
+		if included == key {
+			cycle := append(append([]string{}, including[i:]...), key)
+			return nil, nil, fail("include cycle " + prefix + strings.Join(cycle, " -> "+prefix))
+		}
+	}
+
+	partial, ok := b.Versions[version].Partials[key]
+	if !ok {
+		return nil, nil, fail("partial " + line.Args + " is not in version " + version + ", a version it inherits from, or shared")
+	}
+
+	partialPath := partial.SourcePath + "/" + partial.Storage.Name()
+	content, err := os.ReadFile(partialPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return b.expandDirectives(version, partialPath, content, append(including, key))
+}
@@ -0,0 +1,94 @@
+package book
+
+import (
+	"errors"
+	"github.com/brightercommand/Rewind/internal/config"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"os"
+	"testing"
+)
+
+func TestIncludePartials(t *testing.T) {
+	destPath := publishFixture(t, "includes")
+
+	//version 10 overrides the shared partial, and a partial may include another
+	expected := map[string]string{
+		"contents/9/Outbox.md": "# Outbox\n\n" +
+			"## Setting up the Outbox\n\n" +
+			"Add the Outbox to version 9.\n" +
+			"See [the Outbox](Outbox.md).\n\n" +
+			"Now dispatch your messages.\n",
+		"contents/10/Outbox.md": "# Outbox\n\n" +
+			"## Setting up the Outbox in 10\n\n" +
+			"Use the new Outbox builder.\n\n" +
+			"Now dispatch your messages.\n",
+	}
+
+	for path, want := range expected {
+		content, err := os.ReadFile(destPath + "/" + path)
+		if err != nil {
+			t.Errorf("Error reading %s: %s", path, err)
+			continue
+		}
+		if string(content) != want {
+			t.Errorf("Expected %s to be\n%s\ngot\n%s", path, want, content)
+		}
+	}
+
+	//partials are only published within the docs that include them
+	for _, version := range []string{"9", "10"} {
+		partials := destPath + "/contents/" + version + "/partials"
+		if _, err := os.Stat(partials); !os.IsNotExist(err) {
+			t.Errorf("Expected no partials in version %s, got %v", version, err)
+		}
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	src, sourcePath := findFixture(t, "badincludes")
+
+	expected := sourcePath + "/shared/partials/second.md:3: version 9: " +
+		"include cycle partials/first.md -> partials/second.md -> partials/first.md"
+
+	problems := Validate(src)
+	if problems.Error() != expected {
+		t.Errorf("Expected %s, got %s", expected, problems.Error())
+	}
+}
+
+func TestIncludeMissingPartial(t *testing.T) {
+	b := &Book{
+		Config:   config.Default(),
+		Versions: map[string]pages.Version{"9": {Version: "9"}},
+	}
+
+	tests := []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name:     "missing",
+			doc:      "# Outbox\n<!-- rewind:include partials/outbox-setup.md -->\n",
+			expected: "Outbox.md:2: version 9: partial partials/outbox-setup.md is not in version 9, a version it inherits from, or shared",
+		},
+		{
+			name:     "outside partials",
+			doc:      "<!-- rewind:include Outbox.md -->\n",
+			expected: "Outbox.md:1: version 9: rewind:include must name a partial in partials/, got Outbox.md",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := b.expandDirectives("9", "Outbox.md", []byte(test.doc), nil)
+			var directiveErr *DirectiveError
+			if !errors.As(err, &directiveErr) {
+				t.Fatalf("Expected a DirectiveError, got %v", err)
+			}
+			if err.Error() != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
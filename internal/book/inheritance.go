@@ -0,0 +1,37 @@
+package book
+
+import (
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"strings"
+)
+
+// versionChain returns the versions that a version inherits from, ending with the version itself.
+// The chain is ordered from the oldest base to the version, so that it can be layered over shared in that order, i.e.
+// for 10, which has a base of 9, which has a base of 8, the chain is 8, 9, 10.
+// It returns an error if a base does not exist, or if a version inherits from itself.
+func versionChain(s *sources.Sources, versionName string) ([]pages.Version, error) {
+	var chain []pages.Version
+	visited := make(map[string]bool)
+	var path []string
+
+	name := versionName
+	for name != "" {
+		path = append(path, name)
+		if visited[name] {
+			return nil, fmt.Errorf("inheritance cycle %s", strings.Join(path, " -> "))
+		}
+		visited[name] = true
+
+		version, ok := s.Versions[name]
+		if !ok {
+			return nil, fmt.Errorf("base version %s of version %s does not exist", name, path[len(path)-2])
+		}
+
+		chain = append([]pages.Version{version}, chain...)
+		name = version.Base
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,352 @@
+package book
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/parser"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LinkScheme The scheme of a link to a doc of a particular version, such as rewind://9/Outbox.md, which we resolve to
+// the path of that version's copy of the doc when we publish the book. The version latest means the newest version.
+const LinkScheme = "rewind"
+
+// latestVersion is the version in a link that means the newest version we publish
+const latestVersion = "latest"
+
+// rewriteLinks rewrites the relative links and images, which were written
+// against the folder of its source, so that they resolve from the folder of the version in the book.
+// A link to a doc, or image, of shared or of a layer of the version's chain resolves to the version's own copy, so a link
+// to a doc that the version overrides points at the override. A link into the folder of another version resolves to
+// that version's copy. We leave links to other sites, absolute paths and anchors alone, as we do anything in code.
+// We resolve a link with the LinkScheme to the version it names; see resolveVersionLink.
+// Sources is where each line of the doc came from, so that we report a link on its line in the doc, or partial, that
+// holds it.
+// It returns the rewritten doc, and a BrokenLinkError for each relative link we could not resolve. A link with the
+// LinkScheme that we cannot resolve is an error, as it would never work in the book.
+func (b *Book) rewriteLinks(version string, sourcePath string, fileName string, content []byte, sources lineMap) ([]byte, []error, error) {
+	//docs are at the top of their layer's folder, so its name is the folder the links were written against
+	folder := filepath.Base(sourcePath)
+
+	var broken []error
+	var errs Errors
+	reported := make(map[linkSite]bool)
+	var rewritten bytes.Buffer
+	written := 0
+	for _, site := range linkSites(content) {
+		resolved := site.Destination
+		if strings.HasPrefix(site.Destination, LinkScheme+"://") {
+			var err error
+			resolved, err = b.resolveVersionLink(version, site.Destination)
+			if err != nil {
+				path, line := sources.locate(sourcePath+"/"+fileName, site.Line)
+				errs.Add(&VersionLinkError{
+					Path:    path,
+					Line:    line,
+					Version: version,
+					Link:    site.Destination,
+					Err:     err,
+				})
+				continue
+			}
+		} else {
+			var ok bool
+			resolved, ok = b.resolveLink(version, folder, site.Destination)
+			if !ok {
+				//the links that share a reference definition are broken once, on its line
+				if !reported[site] {
+					reported[site] = true
+					path, line := sources.locate(sourcePath+"/"+fileName, site.Line)
+					broken = append(broken, &BrokenLinkError{
+						Path:    path,
+						Line:    line,
+						Version: version,
+						Link:    site.Destination,
+					})
+				}
+				continue
+			}
+		}
+
+		//links that share a reference definition rewrite it once
+		if resolved != site.Destination && site.Start >= written {
+			rewritten.Write(content[written:site.Start])
+			rewritten.WriteString(resolved)
+			written = site.End
+		}
+	}
+	rewritten.Write(content[written:])
+	return rewritten.Bytes(), broken, errs.OrNil()
+}
+
+// linkSite Where the destination of a link or image is in a doc: the bytes from Start to End, on Line.
+// A link to a reference definition is at the definition. Start is -1, and Line 0, if we could not find the destination.
+type linkSite struct {
+	Destination string
+	Start       int
+	End         int
+	Line        int
+}
+
+// referenceDefinition matches a link reference definition, such as [outbox]: Outbox.md, capturing its destination
+var referenceDefinition = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:[ \t]*<?([^\s>]+)`)
+
+// linkSites returns the site of every link and image in a doc, in the order they appear.
+// The parser does not tell us where its nodes are, so we walk them in order, and look for each from where the last one
+// ended; we also look for each code block and code span, so that we skip them, and what looks like a link in them.
+func linkSites(content []byte) []linkSite {
+	//the parser normalises line endings in place, so give it a copy of the doc
+	doc := parser.NewWithExtensions(parser.CommonExtensions).Parse(append([]byte(nil), content...))
+
+	var sites []linkSite
+	var deferred []string
+	var code [][2]int
+	cursor := 0
+	skip := func(text []byte) bool {
+		i := bytes.Index(content[cursor:], text)
+		if i < 0 {
+			return false
+		}
+		code = append(code, [2]int{cursor + i, cursor + i + len(text)})
+		cursor += i + len(text)
+		return true
+	}
+
+	ast.WalkFunc(doc, func(node ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+
+		var destination string
+		switch n := node.(type) {
+		case *ast.CodeBlock:
+			//a block that is indented, or in a list or quote, is not as it is in the doc, so we skip it a line at a time
+			if !skip(n.Literal) {
+				for _, line := range bytes.Split(n.Literal, []byte("\n")) {
+					if len(bytes.TrimSpace(line)) > 0 {
+						skip(line)
+					}
+				}
+			}
+			return ast.GoToNext
+		case *ast.Code:
+			if !skip(append([]byte("`"), n.Literal...)) {
+				skip(n.Literal)
+			}
+			return ast.GoToNext
+		case *ast.Link:
+			destination = string(n.Destination)
+			if len(n.DeferredID) > 0 {
+				deferred = append(deferred, destination)
+				return ast.GoToNext
+			}
+		case *ast.Image:
+			destination = string(n.Destination)
+		default:
+			return ast.GoToNext
+		}
+
+		if destination == "" {
+			return ast.GoToNext
+		}
+		start := -1
+		for _, prefix := range []string{"](", "](<"} {
+			i := bytes.Index(content[cursor:], []byte(prefix+destination))
+			if i >= 0 && (start < 0 || cursor+i+len(prefix) < start) {
+				start = cursor + i + len(prefix)
+			}
+		}
+		if start < 0 {
+			//a reference image, whose destination is in its definition, or one we cannot find
+			deferred = append(deferred, destination)
+			return ast.GoToNext
+		}
+		sites = append(sites, newLinkSite(content, destination, start))
+		cursor = start + len(destination)
+		return ast.GoToNext
+	})
+
+	if len(deferred) == 0 {
+		return sites
+	}
+
+	definitions := make(map[string]linkSite)
+	for _, match := range referenceDefinition.FindAllSubmatchIndex(content, -1) {
+		destination := string(content[match[2]:match[3]])
+		if _, ok := definitions[destination]; !ok && !inRanges(code, match[2]) {
+			definitions[destination] = newLinkSite(content, destination, match[2])
+		}
+	}
+	for _, destination := range deferred {
+		site, ok := definitions[destination]
+		if !ok {
+			//escaped, or in HTML
+			site = linkSite{Destination: destination, Start: -1}
+		}
+		sites = append(sites, site)
+	}
+	sort.SliceStable(sites, func(i, j int) bool { return sites[i].Start < sites[j].Start })
+	return sites
+}
+
+// newLinkSite the site of a destination that starts at a byte of a doc
+func newLinkSite(content []byte, destination string, start int) linkSite {
+	return linkSite{
+		Destination: destination,
+		Start:       start,
+		End:         start + len(destination),
+		Line:        bytes.Count(content[:start], []byte("\n")) + 1,
+	}
+}
+
+// inRanges whether a byte of a doc is in one of the ranges
+func inRanges(ranges [][2]int, i int) bool {
+	for _, r := range ranges {
+		if i >= r[0] && i < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLink returns the destination of a link in a doc we publish in a version, rewritten to resolve from the folder
+// of the version in the book; folder is the folder, in the sources, that the doc comes from.
+// It returns false if the link is to a file in the sources that we do not publish in the version.
+func (b *Book) resolveLink(version string, folder string, destination string) (string, bool) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return destination, true
+	}
+
+	target := path.Clean(path.Join(folder, u.Path))
+	layer, file, ok := strings.Cut(target, "/")
+	if !ok {
+		return "", false
+	}
+
+	published, ok := b.publishedPath(version, layer, file)
+	if !ok {
+		return "", false
+	}
+
+	resolved, err := b.relativeLink(version, published, u)
+	if err != nil {
+		return "", false
+	}
+	return resolved, true
+}
+
+// resolveVersionLink returns the destination of a link with the LinkScheme, in a doc we publish in a version, rewritten
+// as a relative link from the folder of the version to the doc, or image, of the version the link names
+func (b *Book) resolveVersionLink(version string, destination string) (string, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+
+	target := u.Host
+	if target == latestVersion {
+		target = b.latestVersion()
+	}
+	if _, ok := b.Versions[target]; !ok {
+		return "", fmt.Errorf("there is no version %s", u.Host)
+	}
+
+	file := strings.TrimPrefix(u.Path, "/")
+	published, ok := b.versionFile(target, file)
+	if !ok {
+		return "", fmt.Errorf("version %s has no doc or image %s", target, file)
+	}
+
+	return b.relativeLink(version, published, u)
+}
+
+// latestVersion returns the newest version we publish: the newest release, or if there are only pre-releases, or
+// versions that are not semantic versions, the last of them in order
+func (b *Book) latestVersion() string {
+	var latest, latestRelease *pages.SemVer
+	for name := range b.Versions {
+		v := pages.ParseVersion(name)
+		if latest == nil || pages.CompareVersions(v, *latest) > 0 {
+			latest = &v
+		}
+		if v.Valid && v.PreRelease == "" && (latestRelease == nil || pages.CompareVersions(v, *latestRelease) > 0) {
+			latestRelease = &v
+		}
+	}
+
+	switch {
+	case latestRelease != nil:
+		return latestRelease.Name
+	case latest != nil:
+		return latest.Name
+	default:
+		return ""
+	}
+}
+
+// relativeLink returns a link from the folder of a version to a file published at a path, relative to the root of the
+// book, keeping the query and fragment of the link u
+func (b *Book) relativeLink(version string, published string, u *url.URL) (string, error) {
+	rel, err := filepath.Rel(b.Config.VersionPath(version), published)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := url.URL{Path: filepath.ToSlash(rel), RawQuery: u.RawQuery, Fragment: u.Fragment, RawFragment: u.RawFragment}
+	return resolved.String(), nil
+}
+
+// publishedPath returns where, relative to the root of the book, we publish a file of a layer of the sources when a
+// doc in a version links to it
+func (b *Book) publishedPath(version string, layer string, file string) (string, bool) {
+	target := version
+	if layer != b.Config.SharedFolder && !b.inChain(version, layer) {
+		if _, ok := b.Versions[layer]; !ok {
+			return "", false
+		}
+		target = layer
+	}
+
+	return b.versionFile(target, file)
+}
+
+// versionFile returns where, relative to the root of the book, we publish a doc or image of a version; file is the
+// name of the doc, or the path of the image under the static folder
+func (b *Book) versionFile(version string, file string) (string, bool) {
+	published := b.Versions[version]
+	versionPath := b.Config.VersionPath(version)
+	if _, ok := published.Docs[file]; ok {
+		return versionPath + "/" + file, true
+	}
+
+	imagePath := b.Config.ImagePath() + "/"
+	if strings.HasPrefix(file, imagePath) {
+		if _, ok := published.Images[strings.TrimPrefix(file, imagePath)]; ok {
+			return versionPath + "/" + file, true
+		}
+	}
+	return "", false
+}
+
+// inChain whether a layer is the version, or a version it inherits from
+func (b *Book) inChain(version string, layer string) bool {
+	visited := make(map[string]bool)
+	for name := version; name != "" && !visited[name]; name = b.Versions[name].Base {
+		if name == layer {
+			return true
+		}
+		visited[name] = true
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	destPath := publishFixture(t, "links")
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{
+			//a link into another version's folder resolves to its copy
+			path: "contents/8/Introduction.md",
+			expected: []string{
+				"[outbox](Outbox.md)",
+				"[version 9 outbox](../9/Outbox.md#configuring)",
+				"![Logo](_static/images/Logo.png)",
+				"[guide](https://example.com/guide)",
+				"[the top](#introduction)",
+				"[outbox]: Outbox.md",
+			},
+		},
+		{
+			//version 9 publishes its own outbox, so the link resolves to its own copy
+			path: "contents/9/Introduction.md",
+			expected: []string{
+				"[version 9 outbox](Outbox.md#configuring)",
+				"[outbox]: Outbox.md",
+			},
+		},
+		{
+			//links to shared, and to a base version, resolve to the version's own copies
+			path: "contents/9/Outbox.md",
+			expected: []string{
+				"[introduction](Introduction.md)",
+				"[version 8](Eight.md)",
+				"![Logo](_static/images/Logo.png)",
+			},
+		},
+		{
+			//we leave the same links in code as they are
+			path: "contents/9/Outbox.md",
+			expected: []string{
+				"with `[version 8](../8/Eight.md)`, or:",
+				"```markdown\nBack to the [introduction](../shared/Introduction.md).\n```",
+			},
+		},
+		{
+			//links to a version resolve to the newest version, or the one they name
+			path: "contents/8/Eight.md",
+			expected: []string{
+				"[latest outbox](../9/Outbox.md#configuring)",
+				"[version 9 logo](../9/_static/images/Logo.png)",
+			},
+		},
+		{
+			path: "contents/9/Eight.md",
+			expected: []string{
+				"[latest outbox](Outbox.md#configuring)",
+				"[version 9 logo](_static/images/Logo.png)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		content, err := os.ReadFile(destPath + "/" + test.path)
+		if err != nil {
+			t.Errorf("Error reading %s: %s", test.path, err)
+			continue
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(string(content), expected) {
+				t.Errorf("Expected %s to contain %s, got\n%s", test.path, expected, content)
+			}
+		}
+	}
+}
+
+func TestLinkSites(t *testing.T) {
+	doc := "# Links\r\n\r\n`[a](A.md)` and [a](A.md)\r\n\r\n" +
+		"```\r\n[b][ref]\r\n[ref]: B.md\r\n```\r\n\r\n" +
+		"[b][ref]\r\n\r\n[ref]: B.md\r\n"
+	content := []byte(doc)
+
+	//the links in code are not links, and a link to a reference is at its definition
+	sites := linkSites(content)
+	expected := []linkSite{
+		{Destination: "A.md", Line: 3},
+		{Destination: "B.md", Line: 12},
+	}
+	if len(sites) != len(expected) {
+		t.Fatalf("Expected %d sites, got %+v", len(expected), sites)
+	}
+	for i, site := range sites {
+		if site.Destination != expected[i].Destination || site.Line != expected[i].Line {
+			t.Errorf("Expected %s on line %d, got %+v", expected[i].Destination, expected[i].Line, site)
+		}
+		if string(content[site.Start:site.End]) != site.Destination {
+			t.Errorf("Expected %s at %d, got %q", site.Destination, site.Start, content[site.Start:site.End])
+		}
+	}
+
+	if string(content) != doc {
+		t.Errorf("Expected the doc to be unchanged, got %q", content)
+	}
+}
+
+func TestValidateReportsBrokenLinks(t *testing.T) {
+	src, sourcePath := findFixture(t, "links")
+
+	problems := Validate(src)
+
+	//the same link in code, on an earlier line, is not a link
+	expected := []string{
+		sourcePath + "/8/Eight.md:5: version 8: link Missing.md does not resolve to a doc or image of the version",
+		sourcePath + "/8/Eight.md:5: version 9: link Missing.md does not resolve to a doc or image of the version",
+	}
+
+	if len(problems) != len(expected) {
+		for _, problem := range problems {
+			t.Log(problem.Error())
+		}
+		t.Fatalf("Expected %d problems, got %d", len(expected), len(problems))
+	}
+
+	for i, problem := range problems {
+		var broken *BrokenLinkError
+		if !errors.As(problem, &broken) {
+			t.Errorf("Expected a BrokenLinkError, got %T", problem)
+		}
+		if problem.Error() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], problem.Error())
+		}
+	}
+}
+
+func TestVersionLinksToMissingDocsFail(t *testing.T) {
+	src, sourcePath := findFixture(t, "versionlinks")
+	destPath := t.TempDir() + "/book"
+
+	expected := []string{
+		sourcePath + "/shared/Introduction.md:3: version 9: link rewind://10/Introduction.md: there is no version 10",
+		sourcePath + "/shared/Introduction.md:5: version 9: link rewind://9/Outbox.md: version 9 has no doc or image Outbox.md",
+	}
+
+	problems := Validate(src)
+	if problems.Error() != strings.Join(expected, "\n") {
+		t.Errorf("Expected %s, got %s", strings.Join(expected, "\n"), problems.Error())
+	}
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{NoCache: true})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	err = book.Publish(context.Background())
+	var linkErr *VersionLinkError
+	if !errors.As(err, &linkErr) {
+		t.Errorf("Expected a VersionLinkError, got %v", err)
+	}
+
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be published, got %v", err)
+	}
+}
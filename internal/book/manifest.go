@@ -0,0 +1,178 @@
+package book
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log"
+	"os"
+	"path"
+)
+
+// ManifestFileName The file, in the root of the book, that lists every file Rewind wrote there
+const ManifestFileName = ".rewind-manifest.yaml"
+
+// generatedSource is the source we record for a file that we generate, rather than copy
+const generatedSource = "(generated)"
+
+// Manifest The files Rewind wrote when it last published a book.
+// The next publish uses it to remove the files it no longer writes; we never remove a file that is not in it.
+type Manifest struct {
+	Files []ManifestEntry `yaml:"files"`
+}
+
+// ManifestEntry A file Rewind wrote
+// Path is relative to the root of the book, Source is the file it was copied from, and Hash the SHA-256 of what we wrote.
+type ManifestEntry struct {
+	Path   string `yaml:"path"`
+	Source string `yaml:"source"`
+	Hash   string `yaml:"sha256"`
+}
+
+// readManifest reads the manifest from the root of a book.
+// If there is no manifest, because we have not published to the destination before, it returns an empty manifest.
+func readManifest(destPath string) (*Manifest, error) {
+	file, err := os.ReadFile(destPath + "/" + ManifestFileName)
+	if os.IsNotExist(err) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	err = yaml.Unmarshal(file, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", destPath, ManifestFileName, err)
+	}
+	return &manifest, nil
+}
+
+// write writes the manifest to the root of a book
+func (m *Manifest) write(destPath string) error {
+	return m.writeFile(destPath + "/" + ManifestFileName)
+}
+
+// writeFile writes the manifest to a file
+func (m *Manifest) writeFile(path string) error {
+	file, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, file, 0644)
+}
+
+// union returns a manifest of the files in this manifest, followed by those in the other that this one does not have.
+// Where both have a file we keep this manifest's entry.
+func (m *Manifest) union(other *Manifest) *Manifest {
+	entries := m.entries()
+	union := &Manifest{Files: append([]ManifestEntry(nil), m.Files...)}
+	for _, entry := range other.Files {
+		if _, ok := entries[entry.Path]; !ok {
+			union.Files = append(union.Files, entry)
+		}
+	}
+	return union
+}
+
+// entries returns the entries of the manifest by their path
+func (m *Manifest) entries() map[string]ManifestEntry {
+	entries := make(map[string]ManifestEntry, len(m.Files))
+	for _, entry := range m.Files {
+		entries[entry.Path] = entry
+	}
+	return entries
+}
+
+// staleFiles returns the files in an old manifest that we no longer write, in the order they appear in the manifest
+func (b *Book) staleFiles(old *Manifest) []ManifestEntry {
+	current := make(map[string]bool)
+	for _, out := range b.outputs() {
+		current[out.Path] = true
+	}
+
+	var stale []ManifestEntry
+	for _, entry := range old.Files {
+		if !current[entry.Path] {
+			stale = append(stale, entry)
+		}
+	}
+	return stale
+}
+
+// prune removes the files in an old manifest that we no longer write, and any folders that leaves empty.
+// We leave a file alone if it has changed since we wrote it, as someone else now owns what is in it.
+func (b *Book) prune(old *Manifest) error {
+	for _, entry := range b.staleFiles(old) {
+		filePath := b.Root.DestPath + "/" + entry.Path
+
+		hash, err := hashFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if hash != entry.Hash {
+			log.Print("Not removing " + filePath + " as it has changed since it was published...")
+			continue
+		}
+
+		log.Print("Removing " + filePath + " as it is no longer published...")
+		err = os.Remove(filePath)
+		if err != nil {
+			return err
+		}
+
+		err = removeEmptyFolders(b.Root.DestPath, path.Dir(entry.Path))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeEmptyFolders removes a folder, relative to the root of the book, if it is empty, and then each of its parents
+// that is left empty. It stops at the first folder that is not empty, and never removes the root of the book.
+func removeEmptyFolders(destPath string, folder string) error {
+	for folder != "." && folder != "/" && folder != "" {
+		entries, err := os.ReadDir(destPath + "/" + folder)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return nil
+		}
+
+		err = os.Remove(destPath + "/" + folder)
+		if err != nil {
+			return err
+		}
+		folder = path.Dir(folder)
+	}
+	return nil
+}
+
+// hashFile returns the SHA-256 of a file, as hex
+func hashFile(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,80 @@
+package book
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestPruneStaleFiles(t *testing.T) {
+	src, sourcePath := findFixture(t, "source")
+	destPath := t.TempDir() + "/book"
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Errorf("Error creating book: %s", err)
+	}
+
+	manifest, err := readManifest(destPath)
+	if err != nil {
+		t.Errorf("Error reading manifest: %s", err)
+		return
+	}
+
+	published := make(map[string]ManifestEntry)
+	for _, entry := range manifest.Files {
+		published[entry.Path] = entry
+	}
+
+	if entry := published["contents/9/DocumentTwo.md"]; entry.Source != sourcePath+"/9/DocumentTwo.md" || len(entry.Hash) != 64 {
+		t.Errorf("Expected the manifest to record the source and hash of contents/9/DocumentTwo.md, got %+v", entry)
+	}
+
+	if entry := published["SUMMARY.md"]; entry.Source != generatedSource {
+		t.Errorf("Expected the manifest to record SUMMARY.md as generated, got %+v", entry)
+	}
+
+	//a file we did not write, and a published file that someone has since changed
+	err = os.WriteFile(destPath+"/contents/9/Notes.md", []byte("# Notes\n"), 0644)
+	if err != nil {
+		t.Errorf("Error writing file: %s", err)
+	}
+	err = os.WriteFile(destPath+"/contents/10/DocumentThree.md", []byte("# Changed\n"), 0644)
+	if err != nil {
+		t.Errorf("Error writing file: %s", err)
+	}
+
+	//delete a shared doc and a shared image from the sources
+	delete(src.Shared.Docs, "DocumentThree.md")
+	delete(src.Shared.Images, "diagrams/ImageFive.png")
+
+	//we must force the publish, as the destination now holds a file we did not write
+	book, err = MakeBook(context.Background(), src, destPath, Options{Force: true})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Errorf("Error creating book: %s", err)
+	}
+
+	for _, file := range []string{"contents/9/DocumentThree.md", "contents/9/_static/images/diagrams"} {
+		if _, err := os.Stat(destPath + "/" + file); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed as it is no longer published", file)
+		}
+	}
+
+	for _, file := range []string{"contents/9/Notes.md", "contents/10/DocumentThree.md", "contents/9/_static/images/ImageOne.png"} {
+		if _, err := os.Stat(destPath + "/" + file); err != nil {
+			t.Errorf("Expected %s to be kept: %s", file, err)
+		}
+	}
+}
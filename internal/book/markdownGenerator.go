@@ -2,27 +2,34 @@ package book
 
 import (
 	"fmt"
+	"github.com/brightercommand/Rewind/internal/config"
 	"github.com/brightercommand/Rewind/internal/pages"
-	"os"
+	"io"
 	"strings"
 )
 
 type markdownGenerator struct {
 	destPath string
 	buffer   *strings.Builder
+	config   *config.Config
 }
 
-func newMarkdownGenerator() *markdownGenerator {
+func newMarkdownGenerator(cfg *config.Config) *markdownGenerator {
 	return &markdownGenerator{
 		buffer: &strings.Builder{},
+		config: cfg,
 	}
 }
 
-func (g *markdownGenerator) GenerateSummary(entries []pages.OrderedVersionTocs, summary *os.File) error {
+func (g *markdownGenerator) GenerateSummary(entries []pages.OrderedVersionTocs, summary io.Writer) error {
 
 	for _, toc := range entries {
 		g.WriteVersion(toc.Version)
 		g.WriteLine()
+		if toc.LandingPage != "" {
+			g.WriteLandingPage(toc.LandingPage, toc.Version)
+			g.WriteLine()
+		}
 		for _, section := range toc.Sections {
 			g.WriteSection(section.Name)
 			g.WriteLine()
@@ -31,7 +38,7 @@ func (g *markdownGenerator) GenerateSummary(entries []pages.OrderedVersionTocs,
 		}
 	}
 
-	_, err := summary.WriteString(g.buffer.String())
+	_, err := io.WriteString(summary, g.buffer.String())
 	if err != nil {
 		return err
 	}
@@ -48,20 +55,32 @@ func (g *markdownGenerator) WriteVersion(version string) {
 	g.buffer.WriteString("\n")
 }
 
+// WriteLandingPage writes the link to a version's landing page, which is the first entry for the version.
+func (g *markdownGenerator) WriteLandingPage(file string, version string) {
+	g.buffer.WriteString(g.getListItemWithIndent(version, g.getLinkPath(pages.TOCEntry{File: file}, version), 0))
+	g.buffer.WriteString("\n")
+}
+
 func (g *markdownGenerator) WriteSection(section string) {
 	g.buffer.WriteString(g.getTitle(section, 3))
 	g.buffer.WriteString("\n")
 }
 
 func (g *markdownGenerator) WriteTOCs(s []pages.TOCEntry, version string) {
+	g.writeEntries(s, version, 0)
+}
+
+// writeEntries writes a list item for each entry, followed by its children indented one level deeper
+func (g *markdownGenerator) writeEntries(s []pages.TOCEntry, version string, depth int) {
 	for _, entry := range s {
-		g.buffer.WriteString(g.getListItemWithIndent(entry.Name, g.getLinkPath(entry, version)))
+		g.buffer.WriteString(g.getListItemWithIndent(entry.Name, g.getLinkPath(entry, version), depth))
 		g.buffer.WriteString("\n")
+		g.writeEntries(entry.Children, version, depth+1)
 	}
 }
 
 func (g *markdownGenerator) getLinkPath(entry pages.TOCEntry, version string) string {
-	link := "/" + pages.ContentDirName + "/" + version + "/" + entry.File
+	link := "/" + g.config.VersionPath(version) + "/" + entry.File
 	//spaces in markdown links may cause issues, so replace them with %20
 	nospace := strings.ReplaceAll(link, " ", "%20")
 	return nospace
@@ -71,8 +90,10 @@ func (m *markdownGenerator) getLink(desc, url string) string {
 	return fmt.Sprintf("[%s](%s)", desc, url)
 }
 
-func (m *markdownGenerator) getListItemWithIndent(desc, url string) string {
-	return " " + "*" + " " + m.getLink(desc, url)
+// getListItemWithIndent returns a list item; each level of depth indents it by two spaces, so that it is nested
+// under the list item above it
+func (m *markdownGenerator) getListItemWithIndent(desc, url string, depth int) string {
+	return strings.Repeat("  ", depth) + " " + "*" + " " + m.getLink(desc, url)
 }
 
 func (m *markdownGenerator) getTitle(content string, level int) string {
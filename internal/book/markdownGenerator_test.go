@@ -1,13 +1,14 @@
 package book
 
 import (
+	"github.com/brightercommand/Rewind/internal/config"
 	"github.com/brightercommand/Rewind/internal/pages"
 	"strings"
 	"testing"
 )
 
 func TestMarkdownGenerator(t *testing.T) {
-	generator := newMarkdownGenerator()
+	generator := newMarkdownGenerator(config.Default())
 	generator.WriteVersion("9")
 	generator.WriteLine()
 	generator.WriteSection("BrighterConfiguration")
@@ -42,3 +43,42 @@ func TestMarkdownGenerator(t *testing.T) {
 		t.Errorf("Markdown does not match, expected %s got %s", expected, markdown)
 	}
 }
+
+func TestMarkdownGeneratorLandingPage(t *testing.T) {
+	generator := newMarkdownGenerator(config.Default())
+	generator.WriteVersion("10")
+	generator.WriteLine()
+	generator.WriteLandingPage("README.md", "10")
+
+	markdown := generator.buffer.String()
+	expected := "## 10\n\n * [10](/contents/10/README.md)\n"
+	if markdown != expected {
+		t.Errorf("Markdown does not match, expected %s got %s", expected, markdown)
+	}
+}
+
+func TestMarkdownGeneratorNestedEntries(t *testing.T) {
+	generator := newMarkdownGenerator(config.Default())
+	generator.WriteTOCs([]pages.TOCEntry{
+		{
+			Name: "DocumentOne",
+			File: "DocumentOne.md",
+			Children: []pages.TOCEntry{
+				{
+					Name: "DocumentTwo",
+					File: "DocumentTwo.md",
+					Children: []pages.TOCEntry{
+						{Name: "DocumentThree", File: "DocumentThree.md"},
+					},
+				},
+			},
+		},
+		{Name: "DocumentFour", File: "DocumentFour.md"},
+	}, "9")
+
+	markdown := generator.buffer.String()
+	expected := " * [DocumentOne](/contents/9/DocumentOne.md)\n   * [DocumentTwo](/contents/9/DocumentTwo.md)\n     * [DocumentThree](/contents/9/DocumentThree.md)\n * [DocumentFour](/contents/9/DocumentFour.md)\n"
+	if markdown != expected {
+		t.Errorf("Markdown does not match, expected %s got %s", expected, markdown)
+	}
+}
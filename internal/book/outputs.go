@@ -0,0 +1,196 @@
+package book
+
+import (
+	"github.com/brightercommand/Rewind/internal/pages"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+)
+
+// output A file that we write when we publish the book
+// Path is where the file goes, relative to the root of the book. We copy it from FileName in the folder SourcePath.
+// Generated is true if we write Content in place of copying the file: because we made the file, in which case there is
+// no SourcePath, or because we rewrote it; see render.
+// Version is the version the file belongs to, or empty for the files in the root of the book. Doc is true for the docs
+// of a version, which we render before we write them.
+type output struct {
+	Path       string
+	SourcePath string
+	FileName   string
+	Generated  bool
+	Content    []byte
+	Version    string
+	Doc        bool
+}
+
+// Override A doc or image of a version that a layer of its chain supplies in place of one it would otherwise inherit
+// File is the doc, or the image as <static folder>/<image folder>/<path>; By is the version that supplies it, and
+// Overrides the version, or shared, that it replaces.
+type Override struct {
+	Version   string
+	File      string
+	By        string
+	Overrides string
+}
+
+// outputs returns every file we write when we publish the book, in the order we write them.
+// SUMMARY.md comes first, then the root files, then the docs and images of each version. We write each path once: if
+// two files go to the same path, the last one wins; see uniqueOutputs.
+func (b *Book) outputs() []output {
+	var outputs []output
+
+	outputs = append(outputs, output{
+		Path:      pages.SummaryFileName,
+		FileName:  pages.SummaryFileName,
+		Generated: true,
+		Content:   b.Root.Summary,
+	})
+
+	if b.Root.GitBook != nil {
+		outputs = append(outputs, rootOutput(b.Root.GitBook.Storage.Name(), *b.Root.GitBook))
+	}
+
+	if b.Root.ReadMe != nil {
+		outputs = append(outputs, rootOutput(b.Root.ReadMe.Storage.Name(), *b.Root.ReadMe))
+	}
+
+	//files in the root folder are copied after the README, so a README in the root folder wins
+	for _, key := range sortedKeys(b.Root.Files) {
+		outputs = append(outputs, rootOutput(key, b.Root.Files[key]))
+	}
+
+	for _, versionName := range sortedKeys(b.Versions) {
+		version := b.Versions[versionName]
+		versionPath := b.Config.VersionPath(versionName)
+
+		for _, key := range sortedKeys(version.Docs) {
+			doc := version.Docs[key]
+			outputs = append(outputs, output{
+				Path:       versionPath + "/" + doc.Storage.Name(),
+				SourcePath: doc.SourcePath,
+				FileName:   doc.Storage.Name(),
+				Version:    versionName,
+				Doc:        true,
+			})
+		}
+
+		//images keep their path relative to the images folder, so nested folders are kept
+		for _, key := range sortedKeys(version.Images) {
+			image := version.Images[key]
+			outputs = append(outputs, output{
+				Path:       versionPath + "/" + b.Config.ImagePath() + "/" + key,
+				SourcePath: image.SourcePath,
+				FileName:   image.Storage.Name(),
+				Version:    versionName,
+			})
+		}
+	}
+
+	return uniqueOutputs(outputs)
+}
+
+// uniqueOutputs drops every output but the last for each path, so that we never write two files to the same path at
+// once, or list a path twice in the manifest. This is how a README.md in the root folder wins over the one at the top
+// of the sources.
+func uniqueOutputs(outputs []output) []output {
+	last := make(map[string]int, len(outputs))
+	for i, out := range outputs {
+		last[out.Path] = i
+	}
+
+	unique := make([]output, 0, len(last))
+	for i, out := range outputs {
+		if last[out.Path] == i {
+			unique = append(unique, out)
+		}
+	}
+	return unique
+}
+
+// rootOutput returns the output for a file copied as-is to the root of the book, at the given path
+func rootOutput(key string, doc pages.Doc) output {
+	return output{Path: key, SourcePath: doc.SourcePath, FileName: doc.Storage.Name()}
+}
+
+// source describes where an output comes from: the file we copy it from, or that we generate it
+func (out output) source() string {
+	if out.SourcePath == "" {
+		return generatedSource
+	}
+	return out.SourcePath + "/" + out.FileName
+}
+
+// ReadFile returns what we would publish at a path in the book, relative to its root, without writing the book.
+// It returns an error that wraps fs.ErrNotExist if we publish nothing at the path.
+func (b *Book) ReadFile(filePath string) ([]byte, error) {
+	for _, out := range b.outputs() {
+		if out.Path == filePath {
+			out, _, err := b.render(out)
+			if err != nil {
+				return nil, err
+			}
+			return out.read()
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: filePath, Err: fs.ErrNotExist}
+}
+
+// render returns an output as we write it: for a doc of a version, we render it now, so that we write, and hash, the
+// rendered doc; see renderDoc. It also returns the links of the doc that we could not resolve.
+func (b *Book) render(out output) (output, []error, error) {
+	if !out.Doc || out.Generated {
+		return out, nil, nil
+	}
+
+	content, broken, err := b.renderDoc(out.Version, out.SourcePath, out.FileName)
+	if err != nil {
+		return out, nil, err
+	}
+
+	out.Content = content
+	out.Generated = true
+	return out, broken, nil
+}
+
+// read returns what we write for an output
+func (out output) read() ([]byte, error) {
+	if out.Generated {
+		return out.Content, nil
+	}
+	return os.ReadFile(out.SourcePath + "/" + out.FileName)
+}
+
+// write writes an output to a book whose root is rootPath, copying it from its source unless we generated it
+func (out output) write(rootPath string, logger *log.Logger) error {
+	if !out.Generated {
+		logger.Print("Copying " + out.source() + " to " + rootPath + "/" + out.Path + "...")
+		return copyFile(out.SourcePath, out.dir(rootPath), out.FileName)
+	}
+
+	err := os.MkdirAll(out.dir(rootPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	if out.SourcePath != "" {
+		logger.Print("Writing " + out.source() + " to " + rootPath + "/" + out.Path + "...")
+	} else {
+		logger.Print("Writing " + rootPath + "/" + out.Path + "...")
+	}
+	return os.WriteFile(rootPath+"/"+out.Path, out.Content, 0644)
+}
+
+// dir returns the folder that an output is written to, in a book whose root is rootPath
+func (out output) dir(rootPath string) string {
+	if dir := path.Dir(out.Path); dir != "." {
+		return rootPath + "/" + dir
+	}
+	return rootPath
+}
+
+// addOverride records that a layer of a version's chain supplies a file in place of one the version would inherit
+func (b *Book) addOverride(version string, file string, by string, overrides string) {
+	log.Print("Version " + version + " takes " + file + " from " + by + " in place of " + overrides + "...")
+	b.Overrides = append(b.Overrides, Override{Version: version, File: file, By: by, Overrides: overrides})
+}
@@ -0,0 +1,143 @@
+package book
+
+import (
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/diff"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"io"
+	"os"
+)
+
+// summaryDiffContext how many unchanged lines we show around each change to SUMMARY.md
+const summaryDiffContext = 3
+
+// Plan What publishing a book would do, without doing it
+// Copies lists every file we would write, in the order we would write them. Removes lists the files we published last
+// time that we would remove, as we no longer write them. Overrides lists the docs and images that a version takes from
+// its own folder, or a base, in place of shared. SummaryDiff is a unified diff from the SUMMARY.md in the destination
+// to the one we would write; it is empty if they are the same.
+type Plan struct {
+	Copies      []PlannedCopy
+	Removes     []string
+	Overrides   []Override
+	SummaryDiff string
+}
+
+// PlannedCopy A file we would write
+// Path is relative to the root of the book, Source is the file we would copy. Overwrites is true if there is already a
+// file at Path in the destination that differs from the one we would write; Unchanged is true if it is the same, so we
+// would not need to write it.
+type PlannedCopy struct {
+	Path       string
+	Source     string
+	Overwrites bool
+	Unchanged  bool
+}
+
+// Plan works out what Publish would do, without writing anything to the destination.
+// Call it after MakeBook, in place of Publish.
+func (b *Book) Plan() (*Plan, error) {
+	plan := &Plan{Overrides: b.Overrides}
+
+	//we hash each output as we would write it, and compare it with the file in the destination, if there is one
+	hashes := newBuildCache()
+	for _, out := range b.outputs() {
+		planned := PlannedCopy{Path: out.Path, Source: out.source()}
+		existing, err := hashFile(b.Root.DestPath + "/" + out.Path)
+		if err == nil {
+			out, _, err = b.render(out)
+			if err != nil {
+				return nil, err
+			}
+			hash, err := hashes.contentHash(out)
+			if err != nil {
+				return nil, err
+			}
+			planned.Unchanged = hash == existing
+			planned.Overwrites = !planned.Unchanged
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		plan.Copies = append(plan.Copies, planned)
+	}
+
+	old, err := readManifest(b.Root.DestPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range b.staleFiles(old) {
+		plan.Removes = append(plan.Removes, entry.Path)
+	}
+
+	current, err := os.ReadFile(b.Root.DestPath + "/" + pages.SummaryFileName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	plan.SummaryDiff = diff.Unified("a/"+pages.SummaryFileName, "b/"+pages.SummaryFileName,
+		string(current), string(b.Root.Summary), summaryDiffContext)
+
+	return plan, nil
+}
+
+// Write writes the plan in a form for people to read
+func (p *Plan) Write(w io.Writer) error {
+	var overwrites, unchanged int
+	for _, c := range p.Copies {
+		switch {
+		case c.Overwrites:
+			overwrites++
+		case c.Unchanged:
+			unchanged++
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "Copies (%d, %d overwrite existing files, %d unchanged):\n", len(p.Copies), overwrites,
+		unchanged)
+	if err != nil {
+		return err
+	}
+	for _, c := range p.Copies {
+		action := "create"
+		switch {
+		case c.Overwrites:
+			action = "overwrite"
+		case c.Unchanged:
+			action = "unchanged"
+		}
+		_, err = fmt.Fprintf(w, "  %-9s %s <- %s\n", action, c.Path, c.Source)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "\nRemoves (%d):\n", len(p.Removes))
+	if err != nil {
+		return err
+	}
+	for _, r := range p.Removes {
+		_, err = fmt.Fprintf(w, "  %s\n", r)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "\nOverrides (%d):\n", len(p.Overrides))
+	if err != nil {
+		return err
+	}
+	for _, o := range p.Overrides {
+		_, err = fmt.Fprintf(w, "  version %s: %s from %s overrides %s\n", o.Version, o.File, o.By, o.Overrides)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.SummaryDiff == "" {
+		_, err = fmt.Fprintf(w, "\n%s is unchanged\n", pages.SummaryFileName)
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "\n%s changes:\n%s", pages.SummaryFileName, p.SummaryDiff)
+	return err
+}
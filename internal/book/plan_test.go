@@ -0,0 +1,113 @@
+package book
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	src, sourcePath := findFixture(t, "source")
+	destPath := t.TempDir() + "/book"
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+
+	plan, err := book.Plan()
+	if err != nil {
+		t.Errorf("Error planning book: %s", err)
+		return
+	}
+
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("Expected a plan not to write the book")
+	}
+
+	copies := make(map[string]PlannedCopy)
+	for _, c := range plan.Copies {
+		copies[c.Path] = c
+		if c.Overwrites {
+			t.Errorf("Expected %s not to overwrite anything in an empty destination", c.Path)
+		}
+	}
+
+	if copies["contents/9/DocumentTwo.md"].Source != sourcePath+"/9/DocumentTwo.md" {
+		t.Errorf("Expected version 9 to copy its own DocumentTwo.md, got %s", copies["contents/9/DocumentTwo.md"].Source)
+	}
+
+	if copies["contents/9/DocumentOne.md"].Source != sourcePath+"/shared/DocumentOne.md" {
+		t.Errorf("Expected version 9 to copy the shared DocumentOne.md, got %s", copies["contents/9/DocumentOne.md"].Source)
+	}
+
+	if _, ok := copies["contents/10/_static/images/diagrams/ImageFive.png"]; !ok {
+		t.Errorf("Expected the plan to copy the images of each version")
+	}
+
+	expectedOverrides := []Override{
+		{Version: "10", File: "DocumentOne.md", By: "10", Overrides: "Shared"},
+		{Version: "10", File: "_static/images/ImageOne.png", By: "10", Overrides: "Shared"},
+		{Version: "9", File: "DocumentTwo.md", By: "9", Overrides: "Shared"},
+	}
+	if fmt.Sprint(plan.Overrides) != fmt.Sprint(expectedOverrides) {
+		t.Errorf("Expected overrides %v, got %v", expectedOverrides, plan.Overrides)
+	}
+
+	if !strings.Contains(plan.SummaryDiff, "+## 9\n") {
+		t.Errorf("Expected the whole SUMMARY.md to be added, got:\n%s", plan.SummaryDiff)
+	}
+
+	//once published, planning the same book again leaves every file, and SUMMARY.md, as it is
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Errorf("Error creating book: %s", err)
+	}
+
+	//except a file that has changed in the destination since
+	err = os.WriteFile(destPath+"/contents/9/DocumentTwo.md", []byte("# Changed\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+
+	book, err = MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+
+	plan, err = book.Plan()
+	if err != nil {
+		t.Errorf("Error planning book: %s", err)
+		return
+	}
+
+	for _, c := range plan.Copies {
+		changed := c.Path == "contents/9/DocumentTwo.md"
+		if c.Overwrites != changed || c.Unchanged == changed {
+			t.Errorf("Expected %s to overwrite the published file: %t, got %+v", c.Path, changed, c)
+		}
+	}
+
+	if plan.SummaryDiff != "" {
+		t.Errorf("Expected SUMMARY.md to be unchanged, got:\n%s", plan.SummaryDiff)
+	}
+
+	var out bytes.Buffer
+	err = plan.Write(&out)
+	if err != nil {
+		t.Errorf("Error writing plan: %s", err)
+	}
+
+	header := fmt.Sprintf("Copies (%d, 1 overwrite existing files, %d unchanged):", len(plan.Copies), len(plan.Copies)-1)
+	if !strings.Contains(out.String(), header) ||
+		!strings.Contains(out.String(), "overwrite contents/9/DocumentTwo.md <- "+sourcePath+"/9/DocumentTwo.md") ||
+		!strings.Contains(out.String(), "unchanged contents/9/DocumentOne.md <- "+sourcePath+"/shared/DocumentOne.md") ||
+		!strings.Contains(out.String(), "SUMMARY.md is unchanged") {
+		t.Errorf("Expected the plan to list its copies, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,44 @@
+package book
+
+import (
+	"os"
+)
+
+// renderDoc reads a doc that we publish in a version and renders it: we keep the content whose conditions hold for
+// the version, include its partials and snippets, expand its template, rewrite its links, and strip our keys from its
+// front matter; see expandDirectives, expandTemplate, rewriteLinks and stripFrontMatter.
+// It returns the doc as we publish it, and the links we could not resolve.
+func (b *Book) renderDoc(version string, sourcePath string, fileName string) ([]byte, []error, error) {
+	path := sourcePath + "/" + fileName
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, sources, err := b.expandDirectives(version, path, content, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expanded, err := expandTemplate(path, content, sources, version, b.Versions[version].Vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//a template that adds or removes lines moves those after it, so we no longer know where a line came from
+	if lineCount(expanded) != lineCount(content) {
+		sources = nil
+	}
+
+	content, broken, err := b.rewriteLinks(version, sourcePath, fileName, expanded, sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//we strip the front matter last, so that the lines we expanded keep their place
+	content, err = stripFrontMatter(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, broken, nil
+}
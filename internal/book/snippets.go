@@ -0,0 +1,178 @@
+package book
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// regionStart matches the line that opens a region of a sample, such as #region ConfigureOutbox, or //#region in
+// languages that write the marker as a comment
+var regionStart = regexp.MustCompile(`(?i)^\s*(?:(?://|--|'|<!--|/\*)\s*)?#region\s+(.*?)\s*(?:-->|\*/)?\s*$`)
+
+// regionEnd matches the line that closes a region of a sample, such as #endregion
+var regionEnd = regexp.MustCompile(`(?i)^\s*(?:(?://|--|'|<!--|/\*)\s*)?#endregion\b`)
+
+// lineRange matches a range of lines of a sample, such as L10-L20, or a single line, such as L10
+var lineRange = regexp.MustCompile(`^L(\d+)(?:-L(\d+))?$`)
+
+// snippetLanguages the language of a fenced code block for the extension of a sample; for any other extension we use
+// the extension itself
+var snippetLanguages = map[string]string{
+	".cs":     "csharp",
+	".csproj": "xml",
+	".fs":     "fsharp",
+	".vb":     "vb",
+	".js":     "javascript",
+	".ts":     "typescript",
+	".py":     "python",
+	".ps1":    "powershell",
+	".sh":     "bash",
+	".yml":    "yaml",
+	".md":     "markdown",
+	".txt":    "",
+}
+
+// readSnippet reads the snippet that a snippet directive on a line of a doc names, as a fenced code block.
+// The directive names a sample by its path in the samples folder, which is resolved as a partial is, and then, after a
+// #, either a region of it, marked with #region Name and #endregion, or a range of its lines, such as L10-L20; without
+// a # it is the whole sample. We remove the indent that every line of the snippet shares, and any region markers within
+// it, and escape {{ so that the snippet is not expanded as a template.
+// It returns a DirectiveError if the sample, or the region or lines of it, do not exist.
+func (b *Book) readSnippet(version string, path string, line docLine) ([]byte, error) {
+	fail := func(format string, args ...interface{}) error {
+		return &DirectiveError{Path: path, Line: line.Number, Version: version, Message: fmt.Sprintf(format, args...)}
+	}
+
+	prefix := b.Config.SamplesFolder + "/"
+	name, selector, hasSelector := strings.Cut(line.Args, "#")
+	if !strings.HasPrefix(name, prefix) {
+		return nil, fail("rewind:snippet must name a file in %s, got %s", prefix, line.Args)
+	}
+
+	sample, ok := b.Versions[version].Samples[strings.TrimPrefix(name, prefix)]
+	if !ok {
+		return nil, fail("sample %s is not in version %s, a version it inherits from, or shared", name, version)
+	}
+
+	content, err := os.ReadFile(sample.SourcePath + "/" + sample.Storage.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"), "\n")
+	switch match := lineRange.FindStringSubmatch(selector); {
+	case !hasSelector:
+		lines = withoutRegions(lines)
+	case match != nil:
+		first, _ := strconv.Atoi(match[1])
+		last := first
+		if match[2] != "" {
+			last, _ = strconv.Atoi(match[2])
+		}
+		if first < 1 || last < first || last > len(lines) {
+			return nil, fail("lines %s are not in %s, which has %d lines", selector, name, len(lines))
+		}
+		lines = lines[first-1 : last]
+	default:
+		region, err := findRegion(lines, selector)
+		if err != nil {
+			return nil, fail("%s in %s", err, name)
+		}
+		lines = region
+	}
+
+	return codeBlock(snippetLanguage(name), lines), nil
+}
+
+// findRegion returns the lines of the region of a sample with a name, without the markers of any regions within it
+func findRegion(lines []string, name string) ([]string, error) {
+	for i, text := range lines {
+		match := regionStart.FindStringSubmatch(text)
+		if match == nil || match[1] != name {
+			continue
+		}
+
+		depth := 0
+		for j := i + 1; j < len(lines); j++ {
+			if regionStart.MatchString(lines[j]) {
+				depth++
+			} else if regionEnd.MatchString(lines[j]) {
+				if depth == 0 {
+					return withoutRegions(lines[i+1 : j]), nil
+				}
+				depth--
+			}
+		}
+		return nil, fmt.Errorf("region %s on line %d has no #endregion", name, i+1)
+	}
+	return nil, fmt.Errorf("no region %s", name)
+}
+
+// withoutRegions removes the lines that open or close a region
+func withoutRegions(lines []string) []string {
+	var kept []string
+	for _, text := range lines {
+		if !regionStart.MatchString(text) && !regionEnd.MatchString(text) {
+			kept = append(kept, text)
+		}
+	}
+	return kept
+}
+
+// snippetLanguage the language of the fenced code block for a sample, from its extension
+func snippetLanguage(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if language, ok := snippetLanguages[ext]; ok {
+		return language
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+// codeBlock writes the lines of a snippet as a fenced code block, without the indent that they share, or blank lines
+// at its start and end. The fence is longer than any run of backticks in the snippet, so that the snippet cannot close it.
+func codeBlock(language string, lines []string) []byte {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := sharedIndent(lines)
+	fence := "```"
+	for strings.Contains(strings.Join(lines, "\n"), fence) {
+		fence += "`"
+	}
+
+	var block strings.Builder
+	block.WriteString(fence + language + "\n")
+	for _, text := range lines {
+		text = strings.TrimRight(strings.TrimPrefix(text, indent), " \t\r")
+		block.WriteString(strings.ReplaceAll(text, "{{", templateEscape) + "\n")
+	}
+	block.WriteString(fence + "\n")
+	return []byte(block.String())
+}
+
+// sharedIndent the leading whitespace that every line of a snippet that is not blank starts with
+func sharedIndent(lines []string) string {
+	indent, found := "", false
+	for _, text := range lines {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lead := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+		if !found {
+			indent, found = lead, true
+			continue
+		}
+		for !strings.HasPrefix(lead, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+	return indent
+}
@@ -0,0 +1,125 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSnippets(t *testing.T) {
+	destPath := publishFixture(t, "snippets")
+
+	//version 10 overrides the shared sample; braces in a sample are not a template
+	expected := map[string]string{
+		"contents/9/Outbox.md": "# Outbox\n\n" +
+			"Configure the Outbox in version 9:\n\n" +
+			"```csharp\n" +
+			"services.AddBrighter()\n" +
+			"    .UseOutbox(new InMemoryOutbox())\n" +
+			"    .Log($\"{{Outbox}}\");\n" +
+			"```\n\n" +
+			"Then start the host:\n\n" +
+			"```csharp\nawait host.RunAsync();\n```\n",
+		"contents/10/Outbox.md": "# Outbox\n\n" +
+			"Configure the Outbox in version 10:\n\n" +
+			"```csharp\n" +
+			"services.AddBrighter()\n" +
+			"    .AddProducers(configure => configure.Outbox = new InMemoryOutbox());\n" +
+			"```\n\n" +
+			"Then start the host:\n\n" +
+			"```csharp\nawait host.RunAsync(cancellationToken);\n```\n",
+	}
+
+	for path, want := range expected {
+		content, err := os.ReadFile(destPath + "/" + path)
+		if err != nil {
+			t.Errorf("Error reading %s: %s", path, err)
+			continue
+		}
+		if string(content) != want {
+			t.Errorf("Expected %s to be\n%s\ngot\n%s", path, want, content)
+		}
+	}
+
+	//samples are only published within the docs that take snippets from them
+	if _, err := os.Stat(destPath + "/contents/9/samples"); !os.IsNotExist(err) {
+		t.Errorf("Expected no samples, got %v", err)
+	}
+}
+
+func TestSnippetErrors(t *testing.T) {
+	src, _ := findFixture(t, "snippets")
+
+	book, err := MakeBook(context.Background(), src, "", Options{NoCache: true})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		snippet  string
+		expected string
+	}{
+		{
+			name:     "missing sample",
+			snippet:  "samples/Outbox/Startup.cs#ConfigureOutbox",
+			expected: "sample samples/Outbox/Startup.cs is not in version 9, a version it inherits from, or shared",
+		},
+		{
+			name:     "missing region",
+			snippet:  "samples/Outbox/Program.cs#ConfigureInbox",
+			expected: "no region ConfigureInbox in samples/Outbox/Program.cs",
+		},
+		{
+			name:     "lines past the end",
+			snippet:  "samples/Outbox/Program.cs#L10-L20",
+			expected: "lines L10-L20 are not in samples/Outbox/Program.cs, which has 17 lines",
+		},
+		{
+			name:     "outside samples",
+			snippet:  "Outbox/Program.cs",
+			expected: "rewind:snippet must name a file in samples/, got Outbox/Program.cs",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := "# Outbox\n\n<!-- rewind:snippet " + test.snippet + " -->\n"
+			_, _, err := book.expandDirectives("9", "Outbox.md", []byte(doc), nil)
+			var directiveErr *DirectiveError
+			if !errors.As(err, &directiveErr) {
+				t.Fatalf("Expected a DirectiveError, got %v", err)
+			}
+			if err.Error() != "Outbox.md:3: version 9: "+test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestFindRegion(t *testing.T) {
+	lines := []string{
+		"// #region Outer",
+		"    one",
+		"    // #region Inner",
+		"    two",
+		"    // #endregion",
+		"// #endregion",
+		"#region Unclosed",
+	}
+
+	region, err := findRegion(lines, "Outer")
+	if err != nil {
+		t.Fatalf("Error finding region: %s", err)
+	}
+	if strings.Join(region, "\n") != "    one\n    two" {
+		t.Errorf("Expected the region without its inner markers, got %q", region)
+	}
+
+	_, err = findRegion(lines, "Unclosed")
+	if err == nil || err.Error() != "region Unclosed on line 7 has no #endregion" {
+		t.Errorf("Expected an unclosed region, got %v", err)
+	}
+}
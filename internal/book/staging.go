@@ -0,0 +1,174 @@
+package book
+
+import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/work"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// unionManifestName is the file, in the staging folder, in which we write the union of the old and new manifests before
+// we move it into the destination
+const unionManifestName = ".rewind-manifest-union.yaml"
+
+// stagingPrefix begins the name of the staging folder, which is hidden, so that it is not mistaken for part of a book
+const stagingPrefix = ".rewind-staging-"
+
+// staging A folder in which we build the whole book, before we move it into its destination.
+// It sits next to the destination so that it is on the same file system, which means that we can move files into place
+// by renaming them.
+type staging struct {
+	path string
+}
+
+// newStaging creates a staging folder next to the destination
+func newStaging(destPath string) (*staging, error) {
+	parent := filepath.Dir(filepath.Clean(destPath))
+	err := os.MkdirAll(parent, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	stagingPath, err := os.MkdirTemp(parent, stagingPrefix+filepath.Base(destPath)+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	//a temporary folder is only readable by us, but it may become the book
+	err = os.Chmod(stagingPath, 0755)
+	if err != nil {
+		_ = os.RemoveAll(stagingPath)
+		return nil, err
+	}
+
+	log.Print("Staging book in " + stagingPath + "...")
+	return &staging{path: stagingPath}, nil
+}
+
+// remove removes the staging folder, and anything still in it
+func (s *staging) remove() error {
+	return os.RemoveAll(s.path)
+}
+
+// stage writes every output of the book that has changed since we last wrote it, according to the build cache, to the
+// staging folder, with a manifest of every output of the book.
+// We keep the entry in the old manifest for an output that the changes do not affect, as long as it is still in the
+// destination, rather than looking at its source again.
+// We write up to Options.Jobs outputs at once, and return the errors from all of them.
+// It returns the manifest, and the paths of the outputs it staged.
+// It stops, and returns the context's error, if the context is cancelled.
+func (b *Book) stage(ctx context.Context, stagingPath string, cache *buildCache, old *Manifest, changes Changes) (*Manifest, map[string]bool, error) {
+	outputs := b.outputs()
+	published := old.entries()
+	manifest := Manifest{Files: make([]ManifestEntry, len(outputs))}
+	wrote := make([]bool, len(outputs))
+
+	tasks := make([]work.Task, len(outputs))
+	for i, out := range outputs {
+		i, out := i, out
+		tasks[i] = func(ctx context.Context, logger *log.Logger) error {
+			if entry, ok := published[out.Path]; ok && !changes.affects(out) && entry.Source == out.source() {
+				if _, err := os.Stat(b.Root.DestPath + "/" + out.Path); err == nil {
+					manifest.Files[i] = entry
+					cache.keep(out)
+					return nil
+				}
+			}
+
+			out, broken, err := b.render(out)
+			if err != nil {
+				return err
+			}
+			for _, link := range broken {
+				logger.Print("Warning: " + link.Error())
+			}
+
+			hash, err := cache.contentHash(out)
+			if err != nil {
+				return err
+			}
+			manifest.Files[i] = ManifestEntry{Path: out.Path, Source: out.source(), Hash: hash}
+
+			if cache.unchanged(b.Root.DestPath, out, hash) {
+				logger.Print("Skipping " + out.Path + " as it has not changed...")
+				return nil
+			}
+
+			wrote[i] = true
+			return out.write(stagingPath, logger)
+		}
+	}
+
+	var errs Errors
+	for _, err := range work.Run(ctx, b.Options.Jobs, tasks) {
+		errs.Add(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	staged := make(map[string]bool)
+	for i, out := range outputs {
+		if wrote[i] {
+			staged[out.Path] = true
+		}
+	}
+
+	err := manifest.write(stagingPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &manifest, staged, nil
+}
+
+// swap moves the staged book into the destination.
+// If there is nothing at the destination we rename the whole staging folder. Otherwise we rename each file we staged
+// over the one in the destination, leaving any files we did not stage alone, then call prune to remove the files we no
+// longer write. Before we move anything, we move the union of the old manifest and the new one into the destination,
+// and we move the new manifest last, so that if we fail part way through, the manifest in the destination lists every
+// file we may have written, and the next publish treats them as ours.
+func (s *staging) swap(destPath string, old *Manifest, manifest *Manifest, staged map[string]bool, prune func() error) error {
+	if _, err := os.Stat(destPath); os.IsNotExist(err) {
+		return os.Rename(s.path, destPath)
+	}
+
+	err := old.union(manifest).writeFile(s.path + "/" + unionManifestName)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(s.path+"/"+unionManifestName, destPath+"/"+ManifestFileName)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range manifest.Files {
+		if !staged[entry.Path] {
+			continue
+		}
+
+		dir := path.Dir(entry.Path)
+		if dir != "." {
+			err := os.MkdirAll(destPath+"/"+dir, os.ModePerm)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := os.Rename(s.path+"/"+entry.Path, destPath+"/"+entry.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = prune()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(s.path+"/"+ManifestFileName, destPath+"/"+ManifestFileName)
+}
@@ -0,0 +1,157 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPublishLeavesDestinationAloneOnFailure(t *testing.T) {
+	src, sourcePath := findFixture(t, "source")
+	destPath := t.TempDir() + "/book"
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+
+	err = book.Publish(context.Background())
+	if err != nil {
+		t.Errorf("Error creating book: %s", err)
+	}
+
+	//mark the published book, so that we can tell if it is changed
+	marker := destPath + "/contents/9/DocumentOne.md"
+	err = os.WriteFile(marker, []byte("# Published\n"), 0644)
+	if err != nil {
+		t.Errorf("Error writing file: %s", err)
+	}
+
+	//a cancelled publish
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	book, err = MakeBook(ctx, src, destPath, Options{})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+	cancel()
+
+	err = book.Publish(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the publish to be cancelled, got %v", err)
+	}
+	expectUnchanged(t, marker)
+
+	//a publish that fails part way through
+	book, err = MakeBook(context.Background(), src, destPath, Options{})
+	if err != nil {
+		t.Errorf("Error building book: %s", err)
+		return
+	}
+
+	version := book.Versions["9"]
+	doc := version.Docs["DocumentTwo.md"]
+	doc.SourcePath = sourcePath + "/missing"
+	version.Docs["DocumentTwo.md"] = doc
+
+	err = book.Publish(context.Background())
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the publish to fail as a source is missing, got %v", err)
+	}
+	expectUnchanged(t, marker)
+}
+
+func TestMakeBookCancelled(t *testing.T) {
+	src, _ := findFixture(t, "source")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	book, err := MakeBook(ctx, src, t.TempDir()+"/book", Options{})
+	if !errors.Is(err, context.Canceled) || book != nil {
+		t.Errorf("Expected making the book to be cancelled, got %v", err)
+	}
+}
+
+func TestSwapFailingPartWayListsEveryFileInTheManifest(t *testing.T) {
+	destPath := t.TempDir() + "/book"
+	writeFiles(t, destPath, "Old.md")
+	old := &Manifest{Files: []ManifestEntry{{Path: "Old.md", Source: "Old.md"}}}
+	err := old.write(destPath)
+	if err != nil {
+		t.Fatalf("Error writing manifest: %s", err)
+	}
+
+	//New.md is moved into the destination, then we fail as Missing.md was never staged
+	staging, err := newStaging(destPath)
+	if err != nil {
+		t.Fatalf("Error creating staging folder: %s", err)
+	}
+	defer func() { _ = staging.remove() }()
+	writeFiles(t, staging.path, "New.md")
+	manifest := &Manifest{Files: []ManifestEntry{{Path: "New.md", Source: "New.md"}, {Path: "Missing.md", Source: "Missing.md"}}}
+	err = manifest.write(staging.path)
+	if err != nil {
+		t.Fatalf("Error writing manifest: %s", err)
+	}
+
+	pruned := false
+	err = staging.swap(destPath, old, manifest, map[string]bool{"New.md": true, "Missing.md": true}, func() error {
+		pruned = true
+		return nil
+	})
+	if err == nil || pruned {
+		t.Fatalf("Expected the swap to fail before pruning, got %v", err)
+	}
+
+	if _, err := os.Stat(destPath + "/New.md"); err != nil {
+		t.Fatalf("Expected New.md to have been moved into the destination, got %v", err)
+	}
+
+	published, err := readManifest(destPath)
+	if err != nil {
+		t.Fatalf("Error reading manifest: %s", err)
+	}
+	var paths []string
+	for _, entry := range published.Files {
+		paths = append(paths, entry.Path)
+	}
+	if strings.Join(paths, ",") != "Old.md,New.md,Missing.md" {
+		t.Errorf("Expected the manifest to list the old and new files, got %v", paths)
+	}
+}
+
+// writeFiles writes a doc at each path in a folder
+func writeFiles(t *testing.T, folder string, paths ...string) {
+	for _, path := range paths {
+		err := os.MkdirAll(filepath.Dir(folder+"/"+path), os.ModePerm)
+		if err != nil {
+			t.Fatalf("Error creating folder: %s", err)
+		}
+		err = os.WriteFile(folder+"/"+path, []byte("# "+path+"\n"), 0644)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err)
+		}
+	}
+}
+
+// expectUnchanged checks that a failed publish did not change the marker file in the published book, and removed its
+// staging folder
+func expectUnchanged(t *testing.T, marker string) {
+	content, err := os.ReadFile(marker)
+	if err != nil || string(content) != "# Published\n" {
+		t.Errorf("Expected %s to be unchanged, got %q, %v", marker, content, err)
+	}
+
+	destPath := strings.TrimSuffix(marker, "/contents/9/DocumentOne.md")
+	staged, err := filepath.Glob(filepath.Dir(destPath) + "/" + stagingPrefix + filepath.Base(destPath) + "-*")
+	if err != nil || len(staged) > 0 {
+		t.Errorf("Expected the staging folder to be removed, found %v", staged)
+	}
+}
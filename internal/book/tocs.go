@@ -0,0 +1,86 @@
+package book
+
+import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"github.com/brightercommand/Rewind/internal/work"
+	"log"
+)
+
+// parsedToc A .toc.yaml file that we have read and parsed, or the error we had doing so
+type parsedToc struct {
+	toc *pages.Toc
+	err error
+}
+
+// parseTocs reads and parses the .toc.yaml files of shared and every version, up to Options.Jobs at once, so that
+// building the TOC of each version in its chain does not read the same files again.
+// Any error is returned by loadToc when the file is used, so that it is reported against the version that uses it.
+func (b *Book) parseTocs(ctx context.Context, s *sources.Sources) {
+	type tocFile struct {
+		version string
+		doc     *pages.Doc
+	}
+
+	var files []tocFile
+	if s.Shared.TOC != nil {
+		files = append(files, tocFile{"", s.Shared.TOC})
+	}
+	for _, versionName := range sortedKeys(s.Versions) {
+		if toc := s.Versions[versionName].TOC; toc != nil {
+			files = append(files, tocFile{versionName, toc})
+		}
+	}
+
+	parsed := make([]parsedToc, len(files))
+	tasks := make([]work.Task, len(files))
+	for i, file := range files {
+		i, file := i, file
+		tasks[i] = func(ctx context.Context, logger *log.Logger) error {
+			logger.Print("Parsing " + file.doc.SourcePath + "/" + file.doc.Storage.Name() + "...")
+			toc, err := readToc(file.version, file.doc)
+			parsed[i] = parsedToc{toc: toc, err: err}
+			return nil
+		}
+	}
+	work.Run(ctx, b.Options.Jobs, tasks)
+
+	b.tocs = make(map[string]parsedToc, len(files))
+	for i, file := range files {
+		b.tocs[tocKey(file.doc)] = parsed[i]
+	}
+}
+
+// loadToc returns a TOC we have parsed, or reads and parses it if we have not.
+// It returns a copy, as merging a version's TOC into its chain changes it.
+func (b *Book) loadToc(version string, doc *pages.Doc) (*pages.Toc, error) {
+	parsed, ok := b.tocs[tocKey(doc)]
+	if !ok {
+		return readToc(version, doc)
+	}
+	if parsed.err != nil {
+		return nil, parsed.err
+	}
+	return copyToc(parsed.toc), nil
+}
+
+// tocKey is how we find a parsed TOC file
+func tocKey(doc *pages.Doc) string {
+	return doc.SourcePath + "/" + doc.Storage.Name()
+}
+
+// copyToc makes a deep copy of a TOC
+func copyToc(toc *pages.Toc) *pages.Toc {
+	copied := *toc
+	copied.Exclude = append([]string(nil), toc.Exclude...)
+	copied.Removed = append([]string(nil), toc.Removed...)
+	copied.Hidden = append([]string(nil), toc.Hidden...)
+	copied.Sections = make(map[string]*pages.TOCSection, len(toc.Sections))
+	for name, section := range toc.Sections {
+		s := *section
+		s.Entries = copyEntries(section.Entries)
+		copied.Sections[name] = &s
+	}
+	return &copied
+}
@@ -0,0 +1,96 @@
+package book
+
+import (
+	"github.com/brightercommand/Rewind/internal/pages"
+	"log"
+	"strings"
+)
+
+// excludeFiles removes the docs and images that a version excludes from those it has inherited so far
+// An image is named in an exclude list by the path of the images folder, followed by its path within that folder.
+func (b *Book) excludeFiles(layer pages.Version, bookVersion *pages.Version) {
+	excludedImagePrefix := b.Config.ImagePath() + "/"
+	for _, file := range layer.Exclude {
+		if strings.HasPrefix(file, excludedImagePrefix) {
+			log.Print("Version " + layer.Version + " excludes image " + file + "...")
+			delete(bookVersion.Images, strings.TrimPrefix(file, excludedImagePrefix))
+		} else {
+			log.Print("Version " + layer.Version + " excludes " + file + "...")
+			delete(bookVersion.Docs, file)
+		}
+	}
+}
+
+// applyTombstones removes what the tombstones in a version's chain have asked for from the merged TOC and the
+// version's docs. Entries that point to an excluded file that the version no longer has are dropped from the TOC, and
+// the files of entries removed by a tombstone are no longer published, unless another entry still points to them.
+func (b *Book) applyTombstones(chain []pages.Version, toc *pages.Toc) {
+	version, ok := b.Versions[chain[len(chain)-1].Version]
+	if !ok {
+		return
+	}
+
+	excluded := make(map[string]bool)
+	for _, layer := range chain {
+		for _, file := range layer.Exclude {
+			excluded[file] = true
+		}
+	}
+
+	for _, sectionName := range sortedKeys(toc.Sections) {
+		section := toc.Sections[sectionName]
+		var removed []string
+		section.Entries, removed = pruneEntries(section.Entries, func(entry pages.TOCEntry) bool {
+			_, published := version.Docs[entry.File]
+			return excluded[entry.File] && !published
+		})
+		toc.Removed = append(toc.Removed, removed...)
+	}
+
+	referenced := make(map[string]bool)
+	for _, section := range toc.Sections {
+		for _, file := range entryFiles(section.Entries) {
+			referenced[file] = true
+		}
+	}
+	referenced[toc.LandingPage] = true
+
+	for _, file := range toc.Removed {
+		if !referenced[file] {
+			if _, ok := version.Docs[file]; ok {
+				log.Print("Version " + version.Version + " no longer publishes " + file + "...")
+				delete(version.Docs, file)
+			}
+		}
+	}
+}
+
+// pruneEntries drops the entries, and their children, for which drop returns true, at every level of nesting
+// It returns the remaining entries and the files of those that were dropped.
+func pruneEntries(entries []pages.TOCEntry, drop func(entry pages.TOCEntry) bool) (kept []pages.TOCEntry, removed []string) {
+	kept = make([]pages.TOCEntry, 0, len(entries))
+	for _, entry := range entries {
+		if drop(entry) {
+			removed = append(removed, entryFiles([]pages.TOCEntry{entry})...)
+			continue
+		}
+
+		var removedChildren []string
+		entry.Children, removedChildren = pruneEntries(entry.Children, drop)
+		removed = append(removed, removedChildren...)
+		kept = append(kept, entry)
+	}
+	return kept, removed
+}
+
+// entryFiles returns the files of the entries and all of their children
+func entryFiles(entries []pages.TOCEntry) []string {
+	var files []string
+	for _, entry := range entries {
+		if entry.File != "" {
+			files = append(files, entry.File)
+		}
+		files = append(files, entryFiles(entry.Children)...)
+	}
+	return files
+}
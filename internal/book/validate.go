@@ -0,0 +1,170 @@
+package book
+
+import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/sources"
+)
+
+// Validate merges the docs and TOC of every version, as MakeBook does, but without publishing anything, and reports
+// every problem that it finds in one pass.
+// We report missing .toc.yaml files, .toc.yaml files that cannot be parsed, TOC entries that point to a file that is not
+// a doc in the version, docs in a version that no TOC entry points to, entry names or files used more than once
+// in a version, docs whose template cannot be expanded, and links in a doc that do not resolve to a doc or image of the
+// version.
+func Validate(s *sources.Sources) Errors {
+	b := &Book{
+		Options:  Options{Jobs: s.Jobs},
+		Config:   sourceConfig(s),
+		Root:     &pages.Root{SourcePath: s.Root.SourcePath},
+		Versions: make(map[string]pages.Version),
+	}
+
+	var problems Errors
+
+	b.parseTocs(context.Background(), s)
+	shared, err := b.loadSharedEntries(s)
+	if err != nil {
+		problems.Add(err)
+		shared = &pages.Toc{Sections: make(map[string]*pages.TOCSection)}
+	}
+
+	//only build the TOC for a version if every TOC in its chain can be read
+	readable := make(map[string]bool)
+	for _, versionName := range sortedKeys(s.Versions) {
+		version := s.Versions[versionName]
+		if version.TOC == nil {
+			problems.Add(b.missingToc(versionName, version.SourcePath))
+		} else if _, err := b.loadToc(versionName, version.TOC); err != nil {
+			problems.Add(err)
+		} else {
+			readable[versionName] = true
+		}
+	}
+
+	for _, versionName := range sortedKeys(s.Versions) {
+		bookVersion, err := b.makeVersion(s, s.Versions[versionName])
+		if err != nil {
+			problems.Add(&VersionError{Version: versionName, Err: err})
+			continue
+		}
+		b.Versions[versionName] = *bookVersion
+
+		chain, err := versionChain(s, versionName)
+		if err != nil {
+			continue
+		}
+
+		if !chainReadable(chain, readable) {
+			continue
+		}
+
+		toc, err := b.buildVersionEntries(shared, chain)
+		if err != nil {
+			problems.Add(err)
+			continue
+		}
+		b.applyTombstones(chain, toc)
+
+		err = b.addFrontMatterEntries(versionName, toc)
+		if err != nil {
+			problems.Add(err)
+			continue
+		}
+
+		problems.Add(checkEntries(b.Versions[versionName], toc).OrNil())
+	}
+
+	for _, versionName := range sortedKeys(b.Versions) {
+		problems.Add(b.checkDocs(versionName).OrNil())
+	}
+
+	return problems
+}
+
+// checkDocs checks that we can render every doc of a version, and that every link in them resolves to a doc or image of
+// the version
+func (b *Book) checkDocs(versionName string) Errors {
+	var problems Errors
+	version := b.Versions[versionName]
+	for _, docName := range sortedKeys(version.Docs) {
+		doc := version.Docs[docName]
+		_, broken, err := b.renderDoc(versionName, doc.SourcePath, docName)
+		problems.Add(err)
+		for _, link := range broken {
+			problems.Add(link)
+		}
+	}
+	return problems
+}
+
+func chainReadable(chain []pages.Version, readable map[string]bool) bool {
+	for _, layer := range chain {
+		if !readable[layer.Version] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkEntries checks the merged TOC of a version against the docs of that version
+func checkEntries(version pages.Version, toc *pages.Toc) Errors {
+	var problems Errors
+
+	type entryAt struct {
+		section string
+		entry   string
+	}
+	names := make(map[string]entryAt)
+	files := make(map[string]entryAt)
+	referenced := map[string]bool{toc.LandingPage: true}
+	for _, file := range toc.Hidden {
+		referenced[file] = true
+	}
+
+	var walk func(sectionName string, entries []pages.TOCEntry)
+	walk = func(sectionName string, entries []pages.TOCEntry) {
+		for _, entry := range entries {
+			if other, ok := names[entry.Name]; ok {
+				problems.Add(&DuplicateEntryError{Version: version.Version, Section: sectionName, Entry: entry.Name,
+					OtherSection: other.section, OtherEntry: other.entry})
+			} else {
+				names[entry.Name] = entryAt{section: sectionName, entry: entry.Name}
+			}
+
+			if entry.File == "" {
+				if len(entry.Children) == 0 {
+					problems.Add(&MissingDocError{Version: version.Version, Section: sectionName, Entry: entry.Name})
+				}
+			} else {
+				if other, ok := files[entry.File]; ok {
+					problems.Add(&DuplicateEntryError{Version: version.Version, Section: sectionName, Entry: entry.Name,
+						OtherSection: other.section, OtherEntry: other.entry, File: entry.File})
+				} else {
+					files[entry.File] = entryAt{section: sectionName, entry: entry.Name}
+				}
+
+				if _, ok := version.Docs[entry.File]; !ok {
+					problems.Add(&MissingDocError{Version: version.Version, Section: sectionName, Entry: entry.Name,
+						File: entry.File})
+				}
+				referenced[entry.File] = true
+			}
+
+			walk(sectionName, entry.Children)
+		}
+	}
+
+	for _, sectionName := range sortedKeys(toc.Sections) {
+		walk(sectionName, toc.Sections[sectionName].Entries)
+	}
+
+	for _, docName := range sortedKeys(version.Docs) {
+		if !referenced[docName] {
+			doc := version.Docs[docName]
+			problems.Add(&OrphanDocError{Version: version.Version, Path: doc.SourcePath + "/" + docName, File: docName})
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,82 @@
+package book
+
+import (
+	"errors"
+	"github.com/brightercommand/Rewind/internal/config"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	src, sourcePath := findFixture(t, "invalid")
+
+	problems := Validate(src)
+
+	expected := []string{
+		sourcePath + "/10/.toc.yaml: version 10: no .toc.yaml found",
+		sourcePath + "/9/.toc.yaml:5: version 9: yaml: line 5:",
+		"version 11: entry Eleven Again in section Eleven points to Eleven.md, which entry Introduction also points to",
+		"version 11: entry Introduction in section Overview has the same name as an entry in section Eleven",
+		sourcePath + "/11/Orphan.md: version 11: Orphan.md is not in the TOC",
+		"version 12: entry Missing in section Overview points to Missing.md, which is not a doc in this version",
+	}
+
+	if len(problems) != len(expected) {
+		for _, problem := range problems {
+			t.Log(problem.Error())
+		}
+		t.Fatalf("Expected %d problems, got %d", len(expected), len(problems))
+	}
+
+	for i, problem := range problems {
+		if !strings.HasPrefix(problem.Error(), expected[i]) {
+			t.Errorf("Expected %s, got %s", expected[i], problem.Error())
+		}
+	}
+
+	var parseError *TocParseError
+	if !errors.As(problems[1], &parseError) || parseError.Line != 5 || parseError.Version != "9" {
+		t.Errorf("Expected a TocParseError for version 9 on line 5, got %#v", problems[1])
+	}
+}
+
+func TestValidateValidSources(t *testing.T) {
+	for _, source := range []string{"inheritance", "tombstones"} {
+		src, _ := findFixture(t, source)
+
+		problems := Validate(src)
+		for _, problem := range problems {
+			t.Errorf("%s: expected no problems, got %s", source, problem.Error())
+		}
+	}
+}
+
+func TestValidateNamesConfiguredTocFile(t *testing.T) {
+	//the layout names the TOC file toc.yaml, and v10 has lost its own
+	sourcePath := t.TempDir() + "/config"
+	copyTree(t, fixturePath(t, "config"), sourcePath)
+	err := os.Remove(sourcePath + "/v10/toc.yaml")
+	if err != nil {
+		t.Fatalf("Error removing file: %s", err)
+	}
+
+	cfg, err := config.Load(sourcePath + "/" + config.FileName)
+	if err != nil {
+		t.Fatalf("Error loading configuration: %s", err)
+	}
+
+	src := sources.NewSources()
+	src.Config = cfg
+	err = src.FindFromPath(sourcePath)
+	if err != nil {
+		t.Fatalf("Error finding sources: %s", err)
+	}
+
+	expected := sourcePath + "/v10/toc.yaml: version v10: no toc.yaml found"
+	problems := Validate(src)
+	if problems.Error() != expected {
+		t.Errorf("Expected %s, got %s", expected, problems.Error())
+	}
+}
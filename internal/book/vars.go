@@ -0,0 +1,106 @@
+package book
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+// templateEscape is how a doc writes a literal {{, such as in a code sample, that is not a template action
+const templateEscape = `\{{`
+
+// templateErrorLine finds the line, and the message, in an error from text/template
+var templateErrorLine = regexp.MustCompile(`^template: [^:]*:(\d+):(?:\d+:)? ?(.*)$`)
+
+// templateData What a template in a doc can use: the name of the version we publish the doc in, and its variables
+type templateData struct {
+	Version string
+	Vars    map[string]string
+}
+
+// layerVars returns the template variables of a version: those of shared, then those of each version in its chain, so
+// that a variable of a version overrides one with the same name that it inherits
+func layerVars(s *sources.Sources, chain []pages.Version) (map[string]string, error) {
+	vars := make(map[string]string)
+
+	files := []*pages.Doc{s.Shared.VarsFile}
+	for _, layer := range chain {
+		files = append(files, layer.VarsFile)
+	}
+
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+
+		layer, err := readVars(file)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range layer {
+			vars[name] = value
+		}
+	}
+	return vars, nil
+}
+
+// readVars reads a file of template variables, which maps each name to a value
+func readVars(file *pages.Doc) (map[string]string, error) {
+	path := file.SourcePath + "/" + file.Storage.Name()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	err = yaml.Unmarshal(content, &vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// expandTemplate expands the template actions in a doc for a version, so that {{ .Version }} is the name of the
+// version, and {{ .Vars.Name }} the version's variable Name. Using a variable that the version does not have is an
+// error. \{{ is a literal {{, so a doc can show braces, such as in a code sample.
+// A doc without {{ in it is not a template, and we return it as it is. Sources is where each line of the doc came from,
+// so that we report an error on its line in the doc, or partial, that holds it.
+func expandTemplate(path string, content []byte, sources lineMap, version string, vars map[string]string) ([]byte, error) {
+	if !bytes.Contains(content, []byte("{{")) {
+		return content, nil
+	}
+
+	//we write the escape as an action that outputs {{, so the template engine does not see it as an action
+	escaped := bytes.ReplaceAll(content, []byte(templateEscape), []byte(`{{"{{"}}`))
+
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(escaped))
+	if err != nil {
+		return nil, newTemplateError(path, sources, version, err)
+	}
+
+	var expanded bytes.Buffer
+	err = tmpl.Execute(&expanded, templateData{Version: version, Vars: vars})
+	if err != nil {
+		return nil, newTemplateError(path, sources, version, err)
+	}
+	return expanded.Bytes(), nil
+}
+
+// newTemplateError makes a TemplateError from an error from text/template, taking the line from its message, and
+// finding where that line came from
+func newTemplateError(path string, sources lineMap, version string, err error) *TemplateError {
+	templateErr := &TemplateError{Path: path, Version: version, Message: err.Error(), Err: err}
+	if match := templateErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		templateErr.Path, templateErr.Line = sources.locate(path, line)
+		templateErr.Message = match[2]
+	}
+	return templateErr
+}
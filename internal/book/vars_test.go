@@ -0,0 +1,60 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	destPath := publishFixture(t, "vars")
+
+	//version 10 overrides the NuGet version from shared, but keeps the namespace
+	expected := map[string]string{
+		"contents/9/Install.md": "# Installing version 9\n\n" +
+			"    dotnet add package Paramore.Brighter --version 9.0.0\n\n" +
+			"A Handlebars template of your own is written as {{ .Name }}.\n",
+		"contents/10/Install.md": "# Installing version 10\n\n" +
+			"    dotnet add package Paramore.Brighter --version 10.0.0\n\n" +
+			"A Handlebars template of your own is written as {{ .Name }}.\n",
+	}
+
+	for path, want := range expected {
+		content, err := os.ReadFile(destPath + "/" + path)
+		if err != nil {
+			t.Errorf("Error reading %s: %s", path, err)
+			continue
+		}
+		if string(content) != want {
+			t.Errorf("Expected %s to be\n%s\ngot\n%s", path, want, content)
+		}
+	}
+}
+
+func TestUndefinedTemplateVariable(t *testing.T) {
+	src, sourcePath := findFixture(t, "badvars")
+	destPath := t.TempDir() + "/book"
+
+	expected := sourcePath + `/shared/Doc.md:3: version 9: executing "Doc.md" at <.Vars.Missing>: map has no entry for key "Missing"`
+
+	problems := Validate(src)
+	if problems.Error() != expected {
+		t.Errorf("Expected %s, got %s", expected, problems.Error())
+	}
+
+	book, err := MakeBook(context.Background(), src, destPath, Options{NoCache: true})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	err = book.Publish(context.Background())
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) || templateErr.Line != 3 {
+		t.Errorf("Expected a TemplateError on line 3, got %v", err)
+	}
+
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be published, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// FileName The name of the project configuration file, which we look for in the root of the source folder
+const FileName = "rewind.yaml"
+
+// VersionPlaceholder Where the name of the version goes in Output.VersionFolder
+const VersionPlaceholder = "{version}"
+
+// Config The layout of the source folder and of the book we publish from it.
+// Anything that is not set in rewind.yaml keeps its default.
+type Config struct {
+	// SharedFolder the folder of docs shared by every version
+	SharedFolder string `yaml:"sharedFolder"`
+	// SummaryFolder a folder in the source that is not a version
+	SummaryFolder string `yaml:"summaryFolder"`
+	// RootFolder the folder of files copied as-is to the root of the book
+	RootFolder string `yaml:"rootFolder"`
+	// TocFile the name of the table of contents file in shared and each version
+	TocFile string `yaml:"tocFile"`
+	// GitBookFile the name of the GitBook configuration file
+	GitBookFile string `yaml:"gitBookFile"`
+	// VarsFile the name of the file of template variables in shared and each version
+	VarsFile string `yaml:"varsFile"`
+	// StaticFolder the folder, in shared and each version, that holds static assets
+	StaticFolder string `yaml:"staticFolder"`
+	// ImageFolder the folder, within the static folder, that holds images
+	ImageFolder string `yaml:"imageFolder"`
+	// PartialsFolder the folder, in shared and each version, that holds the partials that docs include
+	PartialsFolder string `yaml:"partialsFolder"`
+	// SamplesFolder the folder, in shared and each version, that holds the source files that docs take snippets from
+	SamplesFolder string `yaml:"samplesFolder"`
+	// ImageExtensions the extensions of the files we treat as images
+	ImageExtensions []string `yaml:"imageExtensions"`
+	// VersionPattern a regular expression that a folder's name must match for it to be a version; if empty, every
+	// folder that is not shared, root or summary is a version
+	VersionPattern string `yaml:"versionPattern"`
+	// Output the layout of the book we publish
+	Output Output `yaml:"output"`
+
+	versionPattern *regexp.Regexp
+}
+
+// Output The layout of the book we publish
+type Output struct {
+	// ContentFolder the folder, in the root of the book, that holds every version
+	ContentFolder string `yaml:"contentFolder"`
+	// VersionFolder the name of the folder for each version, in which {version} is replaced by the name of the version
+	VersionFolder string `yaml:"versionFolder"`
+}
+
+// Default returns the configuration we use if there is no rewind.yaml
+func Default() *Config {
+	return &Config{
+		SharedFolder:    "shared",
+		SummaryFolder:   "summary",
+		RootFolder:      "root",
+		TocFile:         ".toc.yaml",
+		GitBookFile:     ".gitbook.yaml",
+		VarsFile:        "vars.yaml",
+		StaticFolder:    "_static",
+		ImageFolder:     "images",
+		PartialsFolder:  "partials",
+		SamplesFolder:   "samples",
+		ImageExtensions: []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg"},
+		Output: Output{
+			ContentFolder: "contents",
+			VersionFolder: VersionPlaceholder,
+		},
+	}
+}
+
+// Load reads the configuration from a file, over the defaults.
+// If the file does not exist we return the defaults.
+func Load(path string) (*Config, error) {
+	c := Default()
+
+	file, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, c.Validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(file, c)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks that the configuration can be used, and compiles the version pattern.
+// Call it again after changing the configuration.
+func (c *Config) Validate() error {
+	for name, value := range map[string]string{
+		"sharedFolder":         c.SharedFolder,
+		"tocFile":              c.TocFile,
+		"varsFile":             c.VarsFile,
+		"staticFolder":         c.StaticFolder,
+		"imageFolder":          c.ImageFolder,
+		"partialsFolder":       c.PartialsFolder,
+		"samplesFolder":        c.SamplesFolder,
+		"output.versionFolder": c.Output.VersionFolder,
+	} {
+		if value == "" {
+			return fmt.Errorf("%s must not be empty", name)
+		}
+	}
+
+	if !strings.Contains(c.Output.VersionFolder, VersionPlaceholder) {
+		return fmt.Errorf("output.versionFolder %s must contain %s", c.Output.VersionFolder, VersionPlaceholder)
+	}
+
+	c.versionPattern = nil
+	if c.VersionPattern != "" {
+		pattern, err := regexp.Compile(c.VersionPattern)
+		if err != nil {
+			return fmt.Errorf("versionPattern: %w", err)
+		}
+		c.versionPattern = pattern
+	}
+	return nil
+}
+
+// IsVersionFolder whether a folder with this name, which is not shared, root or summary, is a version
+func (c *Config) IsVersionFolder(name string) bool {
+	return c.versionPattern == nil || c.versionPattern.MatchString(name)
+}
+
+// IsImageFile whether a file with this name is an image
+func (c *Config) IsImageFile(filename string) bool {
+	for _, ext := range c.ImageExtensions {
+		if strings.HasSuffix(filename, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionPath returns the path of a version's folder, relative to the root of the book
+func (c *Config) VersionPath(version string) string {
+	folder := strings.ReplaceAll(c.Output.VersionFolder, VersionPlaceholder, version)
+	if c.Output.ContentFolder == "" {
+		return folder
+	}
+	return c.Output.ContentFolder + "/" + folder
+}
+
+// ImagePath returns the path of the images folder, relative to the folder of a version
+func (c *Config) ImagePath() string {
+	return c.StaticFolder + "/" + c.ImageFolder
+}
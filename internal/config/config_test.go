@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadDefaultsWhenMissing(t *testing.T) {
+	c, err := Load(os.TempDir() + "/no-such-folder/" + FileName)
+	if err != nil {
+		t.Errorf("Error loading configuration: %s", err)
+		return
+	}
+
+	if c.SharedFolder != "shared" || c.TocFile != ".toc.yaml" || c.VersionPath("9") != "contents/9" {
+		t.Errorf("Expected the default configuration, got %+v", c)
+	}
+
+	if !c.IsVersionFolder("anything") {
+		t.Errorf("Expected every folder to be a version when there is no version pattern")
+	}
+}
+
+func TestLoadOverDefaults(t *testing.T) {
+	myDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Error getting working directory: %s", err)
+	}
+
+	c, err := Load(strings.Replace(myDir, "internal/config", "test/config", 1) + "/" + FileName)
+	if err != nil {
+		t.Errorf("Error loading configuration: %s", err)
+		return
+	}
+
+	if c.SharedFolder != "common" || c.TocFile != "toc.yaml" || c.ImagePath() != "assets/img" {
+		t.Errorf("Expected the layout from the file, got %+v", c)
+	}
+
+	//not set in the file, so keeps its default
+	if c.GitBookFile != ".gitbook.yaml" || c.RootFolder != "root" {
+		t.Errorf("Expected the defaults for anything not in the file, got %+v", c)
+	}
+
+	if c.VersionPath("v9") != "versions/v9" {
+		t.Errorf("Expected versions/v9, got %s", c.VersionPath("v9"))
+	}
+
+	if !c.IsVersionFolder("v10") || c.IsVersionFolder("drafts") {
+		t.Errorf("Expected only folders matching %s to be versions", c.VersionPattern)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   string
+	}{
+		{"empty toc file", func(c *Config) { c.TocFile = "" }, "tocFile must not be empty"},
+		{"no placeholder", func(c *Config) { c.Output.VersionFolder = "docs" }, "must contain {version}"},
+		{"bad pattern", func(c *Config) { c.VersionPattern = "[" }, "versionPattern"},
+	}
+
+	for _, test := range tests {
+		c := Default()
+		test.change(c)
+		err := c.Validate()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.want, err)
+		}
+	}
+}
+
+func TestVersionPathWithoutContentFolder(t *testing.T) {
+	c := Default()
+	c.Output.ContentFolder = ""
+	c.Output.VersionFolder = "v" + VersionPlaceholder
+
+	if c.VersionPath("9") != "v9" {
+		t.Errorf("Expected v9, got %s", c.VersionPath("9"))
+	}
+}
@@ -0,0 +1,167 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// op what happens to a line when we turn one text into the other
+type op int
+
+const (
+	keep op = iota
+	remove
+	add
+)
+
+// line a line of a diff, and what happens to it
+type line struct {
+	op   op
+	text string
+}
+
+// Unified returns a unified diff that turns text a, named from, into text b, named to.
+// Each hunk has up to context lines of unchanged text around the changes. If the texts are the same it returns "".
+func Unified(from string, to string, a string, b string, context int) string {
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for _, h := range hunks(lines, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+		}
+		writeHunk(&out, lines, h)
+	}
+	return out.String()
+}
+
+// splitLines splits a text into lines, without their line endings
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines finds the shortest edit from a to b, using the longest common subsequence of their lines
+func diffLines(a []string, b []string) []line {
+	//lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			lines = append(lines, line{keep, a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			lines = append(lines, line{remove, a[i]})
+			i++
+		} else {
+			lines = append(lines, line{add, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, line{remove, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, line{add, b[j]})
+	}
+	return lines
+}
+
+// hunk the lines of a diff, from start up to but not including end, that are shown together
+type hunk struct {
+	start int
+	end   int
+}
+
+// hunks groups the changed lines, with the context around them, into hunks; changes that are close enough for their
+// context to overlap share a hunk
+func hunks(lines []line, context int) []hunk {
+	var found []hunk
+	for i, l := range lines {
+		if l.op == keep {
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i + context + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if n := len(found); n > 0 && start <= found[n-1].end {
+			found[n-1].end = end
+		} else {
+			found = append(found, hunk{start, end})
+		}
+	}
+	return found
+}
+
+// writeHunk writes a hunk, with a header that gives the lines it covers in each text
+func writeHunk(out *strings.Builder, lines []line, h hunk) {
+	//count the lines of each text before the hunk, and within it
+	var aStart, bStart, aLen, bLen int
+	for _, l := range lines[:h.start] {
+		if l.op != add {
+			aStart++
+		}
+		if l.op != remove {
+			bStart++
+		}
+	}
+	for _, l := range lines[h.start:h.end] {
+		if l.op != add {
+			aLen++
+		}
+		if l.op != remove {
+			bLen++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, l := range lines[h.start:h.end] {
+		switch l.op {
+		case keep:
+			out.WriteString(" ")
+		case remove:
+			out.WriteString("-")
+		case add:
+			out.WriteString("+")
+		}
+		out.WriteString(l.text)
+		out.WriteString("\n")
+	}
+}
+
+// hunkRange formats the lines a hunk covers in one text; lines are counted from 1, but an empty range is given as the
+// line before it
+func hunkRange(start int, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,55 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\n"
+	b := "one\ntwo\nthree\nfour\nfive\nsix\nseven\nEIGHT\nnine\nten\n"
+
+	got := Unified("a/SUMMARY.md", "b/SUMMARY.md", a, b, 1)
+	want := `--- a/SUMMARY.md
++++ b/SUMMARY.md
+@@ -7,3 +7,4 @@
+ seven
+-eight
++EIGHT
+ nine
++ten
+`
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestUnifiedSeparateHunks(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\n"
+	b := "ONE\ntwo\nthree\nfour\nfive\nSIX\n"
+
+	got := Unified("a", "b", a, b, 1)
+	want := `--- a
++++ b
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+@@ -5,2 +5,2 @@
+ five
+-six
++SIX
+`
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestUnifiedFromNothing(t *testing.T) {
+	got := Unified("a", "b", "", "one\n", 3)
+	want := "--- a\n+++ b\n@@ -0,0 +1 @@\n+one\n"
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	if Unified("a", "b", "same\n", "same\n", 3) != "" {
+		t.Errorf("Expected no diff for the same text")
+	}
+}
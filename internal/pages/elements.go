@@ -5,9 +5,6 @@ import (
 )
 
 const SummaryFileName = "SUMMARY.md"
-const ContentDirName = "contents"
-const StaticFolderName = "_static"
-const ImageFolderName = "images"
 
 // AssetType Where we have an asset that is not a markdown file what is its type
 type AssetType int
@@ -18,6 +15,7 @@ type AssetType int
 const (
 	Undefined AssetType = iota
 	Image
+	Sample
 )
 
 // Asset A binary asset used with the markdown files, such as images.
@@ -36,48 +34,93 @@ type Doc struct {
 }
 
 // Root The root of the book.
+// Files are copied as-is to the root of the book, keyed by their path relative to the root folder.
+// Summary is the SUMMARY.md we generate for the book; we hold it in memory until we publish.
 type Root struct {
 	DestPath   string
 	SourcePath string
 	GitBook    *Doc
 	ReadMe     *Doc
-	WorkDir    string
+	Files      map[string]Doc
+	Summary    []byte
 }
 
 // Shared Assets & Docs shared by all versions of the book
+// VarsFile holds the default template variables of every version, if there are any.
+// Partials are the docs that other docs include, keyed by their path relative to the partials folder; we do not publish them.
+// Samples are the source files that docs take snippets from, keyed by their path relative to the samples folder; we do
+// not publish them either.
 type Shared struct {
-	Docs   map[string]Doc
-	Images map[string]Asset
-	TOC    *Doc
+	SourcePath string
+	Docs       map[string]Doc
+	Images     map[string]Asset
+	Partials   map[string]Doc
+	Samples    map[string]Asset
+	TOC        *Doc
+	VarsFile   *Doc
 }
 
 // Version Docs & Assets for a version of the book
+// ReadMe is the optional landing page for the version, it is also held in Docs.
+// Base is the version this version inherits docs, images and TOC sections from; if empty it inherits only from shared.
+// Exclude lists the docs, and images as _static/images/<path>, that this version does not inherit.
+// VarsFile holds the version's template variables, if it has any. Vars are the variables of a version of the book, after
+// we layer the variables of each version in its chain over those of shared.
+// Partials are the docs that other docs include, keyed by their path relative to the partials folder; we do not publish them.
+// Samples are the source files that docs take snippets from, keyed by their path relative to the samples folder; we do
+// not publish them either.
 type Version struct {
-	DestPath string
-	Docs     map[string]Doc
-	Images   map[string]Asset
-	TOC      *Doc
-	Version  string
+	SourcePath string
+	DestPath   string
+	Docs       map[string]Doc
+	Images     map[string]Asset
+	Partials   map[string]Doc
+	Samples    map[string]Asset
+	TOC        *Doc
+	ReadMe     *Doc
+	Version    string
+	Base       string
+	Exclude    []string
+	VarsFile   *Doc
+	Vars       map[string]string
 }
 
 // Enumerating TOC Entries ----------------------------------------------------
 
 // TOCEntry A table of contents entry.
+// An entry may be nested under another, either by listing it in the Children of its parent, or by giving it an Indent
+// one greater than the entry before it. Indent defaults to 1, a top level entry.
+// Remove is a tombstone: a version uses it to remove the entry with the same name that it would otherwise inherit.
 type TOCEntry struct {
-	Name  string `yaml:"name"`
-	File  string `yaml:"file"`
-	Order int    `yaml:"order"`
+	Name     string     `yaml:"name"`
+	File     string     `yaml:"file"`
+	Order    int        `yaml:"order"`
+	Indent   int        `yaml:"indent"`
+	Remove   bool       `yaml:"remove"`
+	Children []TOCEntry `yaml:"children"`
 }
 
 // TOCSection A table of contents sections - the name of the section is held in the Toc map below.
+// Remove is a tombstone: a version uses it to remove the section with the same name that it would otherwise inherit.
 type TOCSection struct {
 	Order   int        `yaml:"order"`
+	Remove  bool       `yaml:"remove"`
 	Entries []TOCEntry `yaml:"entries"`
 }
 
 // Toc A table of contents with a map of names to section within a table of contents.
+// Base and Exclude are only read from a version's .toc.yaml. Base names the version that this version inherits from.
+// Exclude lists the files this version does not inherit.
+// LandingPage is the file of the version's landing page, if it has one; it is not read from the .toc.yaml file.
+// Removed holds the files of any entries removed by a tombstone when versions are merged; it is not read from the file.
+// Hidden holds the files of docs whose front matter keeps them out of the TOC; it is not read from the file either.
 type Toc struct {
-	Sections map[string]*TOCSection `yaml:"Sections"`
+	Base        string                 `yaml:"base"`
+	Exclude     []string               `yaml:"exclude"`
+	Sections    map[string]*TOCSection `yaml:"Sections"`
+	LandingPage string                 `yaml:"-"`
+	Removed     []string               `yaml:"-"`
+	Hidden      []string               `yaml:"-"`
 }
 
 // OrderedTocSection Versions An ordered array of the sections of the book
@@ -88,13 +131,17 @@ type OrderedTocSection struct {
 }
 
 // VersionedToc A map of versions to a table of contents.
+// VersionOrder is whether Sort lists the versions oldest or newest first.
 type VersionedToc struct {
-	Contents map[string]*Toc
+	Contents     map[string]*Toc
+	VersionOrder VersionOrder
 }
 
 // OrderedVersionTocs The table of contents for a version, ordered by "Version" and "Order"
+// Order is the version parsed from the name of the version.
 type OrderedVersionTocs struct {
-	Version  string
-	Order    int
-	Sections []OrderedTocSection
+	Version     string
+	Order       SemVer
+	LandingPage string
+	Sections    []OrderedTocSection
 }
@@ -0,0 +1,76 @@
+package pages
+
+import "fmt"
+
+// Nest turns the entries of each section in a table of contents into a tree.
+// Entries with an indent are moved into the Children of the entry before them with an indent one less. Indents are
+// relative to the list that holds the entry, so the entries in a Children list start again at an indent of 1.
+// It returns an error if an indent skips a level, or if an entry with children has no page to link to.
+func (t *Toc) Nest() error {
+	for sectionName, section := range t.Sections {
+		entries, err := nestEntries(sectionName, section.Entries)
+		if err != nil {
+			return err
+		}
+
+		err = validateParents(sectionName, entries)
+		if err != nil {
+			return err
+		}
+		section.Entries = entries
+	}
+	return nil
+}
+
+func nestEntries(sectionName string, entries []TOCEntry) ([]TOCEntry, error) {
+
+	var nested []TOCEntry
+	//the path of parents down to the last entry we added, one for each level of indent
+	var parents []*TOCEntry
+
+	for _, entry := range entries {
+		indent := entry.Indent
+		if indent == 0 {
+			indent = 1
+		}
+
+		if indent > len(parents)+1 {
+			return nil, fmt.Errorf("section %s: entry %s has an indent of %d, but the entry before it has an indent of %d",
+				sectionName, entry.Name, indent, len(parents))
+		}
+
+		children, err := nestEntries(sectionName, entry.Children)
+		if err != nil {
+			return nil, err
+		}
+		entry.Children = children
+		entry.Indent = 0
+
+		parents = parents[:indent-1]
+		if len(parents) == 0 {
+			nested = append(nested, entry)
+			parents = append(parents, &nested[len(nested)-1])
+		} else {
+			parent := parents[len(parents)-1]
+			parent.Children = append(parent.Children, entry)
+			parents = append(parents, &parent.Children[len(parent.Children)-1])
+		}
+	}
+
+	return nested, nil
+}
+
+// validateParents checks that every entry with children, at any level, links to a page
+func validateParents(sectionName string, entries []TOCEntry) error {
+	for _, entry := range entries {
+		if len(entry.Children) > 0 && entry.File == "" {
+			return fmt.Errorf("section %s: entry %s has children but no file", sectionName, entry.Name)
+		}
+
+		err := validateParents(sectionName, entry.Children)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package pages
+
+import (
+	"testing"
+)
+
+func TestNestByIndent(t *testing.T) {
+	//arrange
+	toc := &Toc{
+		Sections: map[string]*TOCSection{
+			"SectionOne": {
+				Order: 10,
+				Entries: []TOCEntry{
+					{Name: "DocumentOne", File: "DocumentOne.md", Order: 100},
+					{Name: "DocumentTwo", File: "DocumentTwo.md", Order: 100, Indent: 2},
+					{Name: "DocumentThree", File: "DocumentThree.md", Order: 100, Indent: 3},
+					{Name: "DocumentFour", File: "DocumentFour.md", Order: 200, Indent: 2},
+					{Name: "DocumentFive", File: "DocumentFive.md", Order: 200, Indent: 1},
+				},
+			},
+		},
+	}
+
+	//act
+	err := toc.Nest()
+
+	//assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	entries := toc.Sections["SectionOne"].Entries
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 top level entries, got %d", len(entries))
+	}
+
+	if entries[0].Name != "DocumentOne" || entries[1].Name != "DocumentFive" {
+		t.Errorf("Expected DocumentOne and DocumentFive at the top level, got %s and %s", entries[0].Name, entries[1].Name)
+	}
+
+	children := entries[0].Children
+	if len(children) != 2 || children[0].Name != "DocumentTwo" || children[1].Name != "DocumentFour" {
+		t.Fatalf("Expected DocumentTwo and DocumentFour under DocumentOne, got %v", children)
+	}
+
+	if len(children[0].Children) != 1 || children[0].Children[0].Name != "DocumentThree" {
+		t.Errorf("Expected DocumentThree under DocumentTwo, got %v", children[0].Children)
+	}
+}
+
+func TestNestByChildren(t *testing.T) {
+	//arrange
+	toc := &Toc{
+		Sections: map[string]*TOCSection{
+			"SectionOne": {
+				Entries: []TOCEntry{
+					{
+						Name: "DocumentOne",
+						File: "DocumentOne.md",
+						Children: []TOCEntry{
+							{Name: "DocumentTwo", File: "DocumentTwo.md"},
+							{Name: "DocumentThree", File: "DocumentThree.md", Indent: 2},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	//act
+	err := toc.Nest()
+
+	//assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	children := toc.Sections["SectionOne"].Entries[0].Children
+	if len(children) != 1 || children[0].Name != "DocumentTwo" {
+		t.Fatalf("Expected DocumentTwo under DocumentOne, got %v", children)
+	}
+
+	if len(children[0].Children) != 1 || children[0].Children[0].Name != "DocumentThree" {
+		t.Errorf("Expected DocumentThree under DocumentTwo, got %v", children[0].Children)
+	}
+}
+
+func TestNestSkippedIndent(t *testing.T) {
+	toc := &Toc{
+		Sections: map[string]*TOCSection{
+			"SectionOne": {
+				Entries: []TOCEntry{
+					{Name: "DocumentOne", File: "DocumentOne.md"},
+					{Name: "DocumentTwo", File: "DocumentTwo.md", Indent: 3},
+				},
+			},
+		},
+	}
+
+	err := toc.Nest()
+	if err == nil {
+		t.Errorf("Expected an error for an indent that skips a level")
+	}
+}
+
+func TestNestParentWithoutPage(t *testing.T) {
+	toc := &Toc{
+		Sections: map[string]*TOCSection{
+			"SectionOne": {
+				Entries: []TOCEntry{
+					{Name: "DocumentOne", File: "DocumentOne.md"},
+					{Name: "Group", Indent: 2},
+					{Name: "DocumentTwo", File: "DocumentTwo.md", Indent: 3},
+				},
+			},
+		},
+	}
+
+	err := toc.Nest()
+	if err == nil {
+		t.Errorf("Expected an error for a parent without a page")
+	}
+}
@@ -0,0 +1,209 @@
+package pages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// VersionOrder Whether versions are listed oldest first or newest first
+type VersionOrder int
+
+const (
+	Ascending VersionOrder = iota
+	Descending
+)
+
+// ParseVersionOrder parses the name of a version order, ascending or descending
+func ParseVersionOrder(name string) (VersionOrder, error) {
+	switch strings.ToLower(name) {
+	case "", "asc", "ascending":
+		return Ascending, nil
+	case "desc", "descending":
+		return Descending, nil
+	}
+	return Ascending, fmt.Errorf("unknown version order %s, expected ascending or descending", name)
+}
+
+// SemVer A version parsed from the name of a version folder, such as 9, 9.0.0, v10 or 10.1-preview.
+// Missing minor and patch numbers are zero. Valid is false if the name is not a version, in which case we order it by
+// its Name alone.
+type SemVer struct {
+	Name       string
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+	Valid      bool
+}
+
+// ParseVersion parses the name of a version folder as a semantic version.
+// We accept a leading v, one to three numeric parts, an optional pre-release after a - and ignore any build metadata
+// after a +.
+func ParseVersion(name string) SemVer {
+	version := SemVer{Name: name}
+
+	core := strings.TrimPrefix(strings.TrimPrefix(name, "v"), "V")
+	if i := strings.Index(core, "+"); i >= 0 {
+		core = core[:i]
+	}
+	if i := strings.Index(core, "-"); i >= 0 {
+		version.PreRelease = core[i+1:]
+		core = core[:i]
+		if version.PreRelease == "" {
+			return SemVer{Name: name}
+		}
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return SemVer{Name: name}
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		if part == "" || !isDigits(part) {
+			return SemVer{Name: name}
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return SemVer{Name: name}
+		}
+		numbers[i] = number
+	}
+
+	version.Major, version.Minor, version.Patch = numbers[0], numbers[1], numbers[2]
+	version.Valid = true
+	return version
+}
+
+// String returns the name the version was parsed from
+func (v SemVer) String() string {
+	return v.Name
+}
+
+// CompareVersions returns -1 if a is before b, 0 if they are the same and 1 if a is after b, in ascending order.
+// Valid versions come before names that are not versions, which are ordered with any numbers in them compared by value.
+// A pre-release comes before its release. Versions that are equal, such as 9 and 9.0.0, are ordered by name.
+func CompareVersions(a SemVer, b SemVer) int {
+	if a.Valid != b.Valid {
+		if a.Valid {
+			return -1
+		}
+		return 1
+	}
+
+	if a.Valid {
+		if c := compareInts(a.Major, b.Major); c != 0 {
+			return c
+		}
+		if c := compareInts(a.Minor, b.Minor); c != 0 {
+			return c
+		}
+		if c := compareInts(a.Patch, b.Patch); c != 0 {
+			return c
+		}
+		if c := comparePreRelease(a.PreRelease, b.PreRelease); c != 0 {
+			return c
+		}
+	}
+
+	if c := compareNatural(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// comparePreRelease compares pre-release tags as semver does: a release comes after any pre-release, and tags are
+// compared by their dot separated identifiers, numbers by value and before any identifier that is not a number.
+func comparePreRelease(a string, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	aIds := strings.Split(a, ".")
+	bIds := strings.Split(b, ".")
+	for i := 0; i < len(aIds) && i < len(bIds); i++ {
+		aNumeric, bNumeric := isDigits(aIds[i]), isDigits(bIds[i])
+		switch {
+		case aNumeric && bNumeric:
+			aNumber, _ := strconv.Atoi(aIds[i])
+			bNumber, _ := strconv.Atoi(bIds[i])
+			if c := compareInts(aNumber, bNumber); c != 0 {
+				return c
+			}
+		case aNumeric:
+			return -1
+		case bNumeric:
+			return 1
+		default:
+			if c := strings.Compare(aIds[i], bIds[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInts(len(aIds), len(bIds))
+}
+
+// compareNatural compares two strings, treating each run of digits as a number, so that preview2 is before preview10
+func compareNatural(a string, b string) int {
+	aChunks, bChunks := chunk(a), chunk(b)
+	for i := 0; i < len(aChunks) && i < len(bChunks); i++ {
+		if isDigits(aChunks[i]) && isDigits(bChunks[i]) {
+			aNumber, aErr := strconv.Atoi(aChunks[i])
+			bNumber, bErr := strconv.Atoi(bChunks[i])
+			if aErr == nil && bErr == nil {
+				if c := compareInts(aNumber, bNumber); c != 0 {
+					return c
+				}
+				continue
+			}
+		}
+		if c := strings.Compare(strings.ToLower(aChunks[i]), strings.ToLower(bChunks[i])); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aChunks), len(bChunks))
+}
+
+// chunk splits a string into runs of digits and runs of anything else
+func chunk(s string) []string {
+	var chunks []string
+	start := 0
+	for i := 1; i <= len(s); i++ {
+		if i == len(s) || unicode.IsDigit(rune(s[i])) != unicode.IsDigit(rune(s[start])) {
+			chunks = append(chunks, s[start:i])
+			start = i
+		}
+	}
+	return chunks
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package pages
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		major      int
+		minor      int
+		patch      int
+		preRelease string
+		valid      bool
+	}{
+		{"9", 9, 0, 0, "", true},
+		{"9.0.0", 9, 0, 0, "", true},
+		{"v10", 10, 0, 0, "", true},
+		{"V10.2", 10, 2, 0, "", true},
+		{"10.1-preview", 10, 1, 0, "preview", true},
+		{"10.1.2-rc.1+build.5", 10, 1, 2, "rc.1", true},
+		{"nightly", 0, 0, 0, "", false},
+		{"10.1.2.3", 0, 0, 0, "", false},
+		{"10-", 0, 0, 0, "", false},
+	}
+
+	for _, test := range tests {
+		version := ParseVersion(test.name)
+		if version.Valid != test.valid {
+			t.Errorf("%s: expected valid to be %t", test.name, test.valid)
+			continue
+		}
+		if version.Major != test.major || version.Minor != test.minor || version.Patch != test.patch || version.PreRelease != test.preRelease {
+			t.Errorf("%s: expected %d.%d.%d-%s, got %d.%d.%d-%s", test.name, test.major, test.minor, test.patch, test.preRelease,
+				version.Major, version.Minor, version.Patch, version.PreRelease)
+		}
+		if version.Name != test.name {
+			t.Errorf("%s: expected the name to be kept, got %s", test.name, version.Name)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	names := []string{"nightly", "10.1", "10.1-preview.10", "v10", "10.1-preview.2", "9.0.0", "10.1-preview", "9", "v2-beta"}
+	expected := []string{"v2-beta", "9", "9.0.0", "v10", "10.1-preview", "10.1-preview.2", "10.1-preview.10", "10.1", "nightly"}
+
+	versions := make([]SemVer, 0, len(names))
+	for _, name := range names {
+		versions = append(versions, ParseVersion(name))
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+
+	for i, version := range versions {
+		if version.Name != expected[i] {
+			t.Errorf("Expected %s at %d, got %s", expected[i], i, version.Name)
+		}
+	}
+}
+
+func TestParseVersionOrder(t *testing.T) {
+	order, err := ParseVersionOrder("descending")
+	if err != nil || order != Descending {
+		t.Errorf("Expected descending, got %d, %v", order, err)
+	}
+
+	order, err = ParseVersionOrder("")
+	if err != nil || order != Ascending {
+		t.Errorf("Expected ascending by default, got %d, %v", order, err)
+	}
+
+	_, err = ParseVersionOrder("sideways")
+	if err == nil {
+		t.Errorf("Expected an error for an unknown order")
+	}
+}
@@ -1,20 +1,26 @@
 package pages
 
-import "strconv"
+import "sort"
 
+// Sort turns a VersionedToc into an array of OrderedVersionTocs, with the versions, their sections and the entries in
+// those sections in order.
+// The order is fully determined, so that the same sources always give the same SUMMARY.md: sections with the same order
+// are ordered by name, and entries with the same order by name and then file. Entries that are the same on all of these
+// keep the order they were merged in.
 func (t *VersionedToc) Sort() (ordered []OrderedVersionTocs) {
 
 	//Turn a VersionedToc into an array of OrderedVersionToc
 	for versionName, toc := range t.Contents {
-		order, _ := strconv.Atoi(versionName)
 		orderedVersion := OrderedVersionTocs{
-			Version: versionName,
-			Order:   order,
+			Version:     versionName,
+			Order:       ParseVersion(versionName),
+			LandingPage: toc.LandingPage,
 		}
 
 		for sectionName, section := range toc.Sections {
 			orderedSection := OrderedTocSection{
 				Name:    sectionName,
+				Order:   section.Order,
 				Section: section,
 			}
 			orderedVersion.Sections = append(orderedVersion.Sections, orderedSection)
@@ -27,37 +33,47 @@ func (t *VersionedToc) Sort() (ordered []OrderedVersionTocs) {
 	//Now sort the OrderedVersionToc by their order fields
 
 	//sort the versions
-	for i := 0; i < len(ordered); i++ {
-		for j := i + 1; j < len(ordered); j++ {
-			if ordered[i].Order > ordered[j].Order {
-				ordered[i], ordered[j] = ordered[j], ordered[i]
-			}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if t.VersionOrder == Descending {
+			return CompareVersions(ordered[i].Order, ordered[j].Order) > 0
 		}
-	}
+		return CompareVersions(ordered[i].Order, ordered[j].Order) < 0
+	})
 
 	//now for each of the versions, sort their sections
-	for i := 0; i < len(ordered); i++ {
-		for k := 0; k < len(ordered[i].Sections); k++ {
-			for l := k + 1; l < len(ordered[i].Sections); l++ {
-				if ordered[i].Sections[k].Section.Order > ordered[i].Sections[l].Section.Order {
-					ordered[i].Sections[k], ordered[i].Sections[l] = ordered[i].Sections[l], ordered[i].Sections[k]
-				}
+	for i := range ordered {
+		sections := ordered[i].Sections
+		sort.SliceStable(sections, func(k, l int) bool {
+			if sections[k].Order != sections[l].Order {
+				return sections[k].Order < sections[l].Order
 			}
-		}
+			return sections[k].Name < sections[l].Name
+		})
 	}
 
 	// now sort the entries in those sections
-	for i := 0; i < len(ordered); i++ {
-		for j := 0; j < len(ordered[i].Sections); j++ {
-			for k := 0; k < len(ordered[i].Sections[j].Section.Entries); k++ {
-				for l := k + 1; l < len(ordered[i].Sections[j].Section.Entries); l++ {
-					if ordered[i].Sections[j].Section.Entries[k].Order > ordered[i].Sections[j].Section.Entries[l].Order {
-						ordered[i].Sections[j].Section.Entries[k], ordered[i].Sections[j].Section.Entries[l] = ordered[i].Sections[j].Section.Entries[l], ordered[i].Sections[j].Section.Entries[k]
-					}
-				}
-			}
+	for i := range ordered {
+		for j := range ordered[i].Sections {
+			sortEntries(ordered[i].Sections[j].Section.Entries)
 		}
 	}
 
 	return ordered
 }
+
+// sortEntries sorts entries by their order, name and file, and then the children of each entry
+func sortEntries(entries []TOCEntry) {
+	sort.SliceStable(entries, func(k, l int) bool {
+		if entries[k].Order != entries[l].Order {
+			return entries[k].Order < entries[l].Order
+		}
+		if entries[k].Name != entries[l].Name {
+			return entries[k].Name < entries[l].Name
+		}
+		return entries[k].File < entries[l].File
+	})
+
+	for k := range entries {
+		sortEntries(entries[k].Children)
+	}
+}
@@ -20,14 +20,31 @@ func TestToSectionSort(t *testing.T) {
 	checkVersion10(t, orderedVersionTocs[1])
 }
 
+func TestToSectionSortDescending(t *testing.T) {
+	//arrange
+	var book = VersionedToc{
+		Contents:     make(map[string]*Toc),
+		VersionOrder: Descending,
+	}
+
+	book.Contents["9"] = makeVersion9()
+	book.Contents["10"] = makeVersion10()
+
+	//act
+	orderedVersionTocs := book.Sort()
+
+	checkVersion10(t, orderedVersionTocs[0])
+	checkVersion9(t, orderedVersionTocs[1])
+}
+
 func checkVersion9(t *testing.T, orderedVersionToc OrderedVersionTocs) {
 
 	if orderedVersionToc.Version != "9" {
 		t.Errorf("Expected 9 to be first, got %s", orderedVersionToc.Version)
 	}
 
-	if orderedVersionToc.Order != 9 {
-		t.Errorf("Expected 9 to be first, got %d", orderedVersionToc.Order)
+	if orderedVersionToc.Order.Major != 9 {
+		t.Errorf("Expected 9 to be first, got %d", orderedVersionToc.Order.Major)
 	}
 
 	//assert
@@ -71,8 +88,8 @@ func checkVersion10(t *testing.T, orderedVersionToc OrderedVersionTocs) {
 		t.Errorf("Expected 10 to be first, got %s", orderedVersionToc.Version)
 	}
 
-	if orderedVersionToc.Order != 10 {
-		t.Errorf("Expected 10 to be first, got %d", orderedVersionToc.Order)
+	if orderedVersionToc.Order.Major != 10 {
+		t.Errorf("Expected 10 to be first, got %d", orderedVersionToc.Order.Major)
 	}
 
 	//assert
@@ -102,10 +119,10 @@ func checkVersion10(t *testing.T, orderedVersionToc OrderedVersionTocs) {
 func makeVersion9() (toc *Toc) {
 
 	toc = &Toc{
-		Sections: make(map[string]TOCSection),
+		Sections: make(map[string]*TOCSection),
 	}
 
-	toc.Sections["SectionTwo"] = TOCSection{
+	toc.Sections["SectionTwo"] = &TOCSection{
 		Order: 10,
 		Entries: []TOCEntry{
 			{
@@ -121,7 +138,7 @@ func makeVersion9() (toc *Toc) {
 		},
 	}
 
-	toc.Sections["SectionThree"] = TOCSection{
+	toc.Sections["SectionThree"] = &TOCSection{
 		Order: 15,
 		Entries: []TOCEntry{
 			{
@@ -137,7 +154,7 @@ func makeVersion9() (toc *Toc) {
 		},
 	}
 
-	toc.Sections["SectionOne"] = TOCSection{
+	toc.Sections["SectionOne"] = &TOCSection{
 		Order: 5,
 		Entries: []TOCEntry{
 			{
@@ -158,10 +175,10 @@ func makeVersion9() (toc *Toc) {
 func makeVersion10() (toc *Toc) {
 
 	toc = &Toc{
-		Sections: make(map[string]TOCSection),
+		Sections: make(map[string]*TOCSection),
 	}
 
-	toc.Sections["SectionTwo"] = TOCSection{
+	toc.Sections["SectionTwo"] = &TOCSection{
 		Order: 10,
 		Entries: []TOCEntry{
 			{
@@ -177,7 +194,7 @@ func makeVersion10() (toc *Toc) {
 		},
 	}
 
-	toc.Sections["SectionThree"] = TOCSection{
+	toc.Sections["SectionThree"] = &TOCSection{
 		Order: 15,
 		Entries: []TOCEntry{
 			{
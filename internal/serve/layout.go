@@ -0,0 +1,56 @@
+package serve
+
+import (
+	htmltemplate "html/template"
+)
+
+// layout The HTML page we render each doc into, with the navigation of its version on the left.
+// The script at the end reloads the page when the server sends a reload event.
+var layout = htmltemplate.Must(htmltemplate.New("layout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { display: flex; margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; }
+nav { width: 18rem; min-height: 100vh; padding: 1rem; background: #f6f8fa; border-right: 1px solid #d0d7de; }
+nav h3 { margin: 1rem 0 0.25rem; font-size: 0.8rem; text-transform: uppercase; color: #57606a; }
+nav ul { list-style: none; margin: 0; padding-left: 0.75rem; }
+nav a { color: #24292f; text-decoration: none; }
+nav a.current { font-weight: bold; color: #0969da; }
+main { flex: 1; max-width: 50rem; padding: 1rem 2rem; }
+.error { padding: 1rem; background: #ffebe9; border: 1px solid #ff8182; white-space: pre-wrap; font-family: monospace; }
+pre { padding: 1rem; background: #f6f8fa; overflow: auto; }
+</style>
+</head>
+<body>
+<nav>
+{{- if .Versions}}
+<select onchange="window.location = this.value">
+{{- range .Versions}}
+<option value="{{.URL}}"{{if .Current}} selected{{end}}>{{.Name}}</option>
+{{- end}}
+</select>
+{{- end}}
+{{- range .Sections}}
+{{- if .Name}}<h3>{{.Name}}</h3>{{end}}
+{{template "entries" .Entries}}
+{{- end}}
+</nav>
+<main>
+{{- if .Error}}
+<div class="error">{{.Error}}</div>
+{{- end}}
+{{.Content}}
+</main>
+<script>
+new EventSource("{{.EventsPath}}").onmessage = function () { window.location.reload(); };
+</script>
+</body>
+</html>
+{{define "entries"}}<ul>
+{{- range .}}
+<li><a href="{{.URL}}"{{if .Current}} class="current"{{end}}>{{.Name}}</a>{{if .Children}}{{template "entries" .Children}}{{end}}</li>
+{{- end}}
+</ul>{{end}}
+`))
@@ -0,0 +1,264 @@
+package serve
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/brightercommand/Rewind/internal/book"
+	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+	htmltemplate "html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventsPath is the path of the stream of server-sent events that tells a page to reload
+const EventsPath = "/_rewind/events"
+
+// Server Serves a book over HTTP, without publishing it, so that writers can preview it.
+// Markdown docs are rendered as HTML pages, with the table of contents of their version to navigate by, and a switcher
+// to move between versions; anything else is served as it is. Each page listens for server-sent events, and reloads
+// when we are given a new book, or a build fails.
+// It is safe to use from more than one goroutine at once.
+type Server struct {
+	mu     sync.RWMutex
+	book   *book.Book
+	err    error
+	reload chan struct{}
+}
+
+// New returns a server with no book; it serves an error until it is given one with SetBook or SetError
+func New() *Server {
+	return &Server{err: errors.New("the book has not been built yet"), reload: make(chan struct{})}
+}
+
+// SetBook serves a new book, and tells every open page to reload
+func (s *Server) SetBook(b *book.Book) {
+	s.update(b, nil)
+}
+
+// SetError reports that the book failed to build, and tells every open page to reload so that it shows the error.
+// We keep serving the last book that built, under the error.
+func (s *Server) SetError(err error) {
+	s.update(nil, err)
+}
+
+// update swaps in a new book, or error, and closes the reload channel to wake every page that is listening
+func (s *Server) update(b *book.Book, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b != nil {
+		s.book = b
+	}
+	s.err = err
+	close(s.reload)
+	s.reload = make(chan struct{})
+}
+
+// ServeHTTP serves a page or file of the book, the stream of reload events, or a redirect from the root of the book to
+// the landing page of the first version
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == EventsPath {
+		s.serveEvents(w, r)
+		return
+	}
+
+	s.mu.RLock()
+	b, buildErr := s.book, s.err
+	s.mu.RUnlock()
+
+	if b == nil {
+		s.servePage(w, page{Title: "Rewind", Error: buildErr.Error()})
+		return
+	}
+
+	if r.URL.Path == "/" {
+		if len(b.Tocs) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, landingPage(b, b.Tocs[0]), http.StatusFound)
+		return
+	}
+
+	filePath := strings.TrimPrefix(r.URL.Path, "/")
+	content, err := b.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if path.Ext(filePath) != ".md" {
+		http.ServeContent(w, r, path.Base(filePath), time.Time{}, bytes.NewReader(content))
+		return
+	}
+
+	p := newPage(b, r.URL.EscapedPath(), content)
+	if buildErr != nil {
+		p.Error = buildErr.Error()
+	}
+	s.servePage(w, p)
+}
+
+// serveEvents streams a reload event each time we are given a new book, or an error, until the page goes away
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		s.mu.RLock()
+		reload := s.reload
+		s.mu.RUnlock()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-reload:
+			_, err := fmt.Fprint(w, "data: reload\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// servePage renders a page with our layout
+func (s *Server) servePage(w http.ResponseWriter, p page) {
+	p.EventsPath = EventsPath
+
+	var buffer bytes.Buffer
+	err := layout.Execute(&buffer, p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(buffer.Bytes()); err != nil {
+		log.Print("Could not write page: " + err.Error())
+	}
+}
+
+// page What we show for a doc of the book
+// Versions is the version switcher, and Sections the table of contents of the doc's version; Content is the doc
+// rendered as HTML. Error is the error from the last build, if it failed.
+type page struct {
+	Title      string
+	Versions   []link
+	Sections   []section
+	Content    htmltemplate.HTML
+	Error      string
+	EventsPath string
+}
+
+// link A link in the navigation; Current is true if it is the page, or version, we are showing
+type link struct {
+	Name     string
+	URL      string
+	Current  bool
+	Children []link
+}
+
+// section A section of the table of contents of a version
+type section struct {
+	Name    string
+	Entries []link
+}
+
+// newPage makes the page for the doc at a URL path, rendering its markdown and the navigation of its version
+func newPage(b *book.Book, urlPath string, content []byte) page {
+	p := page{Title: path.Base(urlPath), Content: htmltemplate.HTML(render(content))}
+
+	toc, ok := versionOf(b, urlPath)
+	for _, t := range b.Tocs {
+		p.Versions = append(p.Versions, link{Name: t.Version, URL: landingPage(b, t), Current: ok && t.Version == toc.Version})
+	}
+	if !ok {
+		return p
+	}
+
+	p.Title = toc.Version + " - " + p.Title
+	if toc.LandingPage != "" {
+		p.Sections = append(p.Sections, section{Entries: []link{{
+			Name:    toc.Version,
+			URL:     docURL(b, toc.Version, toc.LandingPage),
+			Current: docURL(b, toc.Version, toc.LandingPage) == urlPath,
+		}}})
+	}
+	for _, s := range toc.Sections {
+		p.Sections = append(p.Sections, section{Name: s.Name, Entries: entryLinks(b, toc.Version, s.Section.Entries, urlPath)})
+	}
+	return p
+}
+
+// entryLinks returns the links for the entries of a section, and their children
+func entryLinks(b *book.Book, version string, entries []pages.TOCEntry, urlPath string) []link {
+	var links []link
+	for _, entry := range entries {
+		u := docURL(b, version, entry.File)
+		links = append(links, link{
+			Name:     entry.Name,
+			URL:      u,
+			Current:  u == urlPath,
+			Children: entryLinks(b, version, entry.Children, urlPath),
+		})
+	}
+	return links
+}
+
+// versionOf returns the table of contents of the version that a URL path is in
+func versionOf(b *book.Book, urlPath string) (pages.OrderedVersionTocs, bool) {
+	for _, toc := range b.Tocs {
+		if strings.HasPrefix(urlPath, "/"+b.Config.VersionPath(toc.Version)+"/") {
+			return toc, true
+		}
+	}
+	return pages.OrderedVersionTocs{}, false
+}
+
+// landingPage returns the URL of the first page of a version: its landing page, or else its first entry
+func landingPage(b *book.Book, toc pages.OrderedVersionTocs) string {
+	if toc.LandingPage != "" {
+		return docURL(b, toc.Version, toc.LandingPage)
+	}
+	for _, s := range toc.Sections {
+		if len(s.Section.Entries) > 0 {
+			return docURL(b, toc.Version, s.Section.Entries[0].File)
+		}
+	}
+	return "/" + b.Config.VersionPath(toc.Version) + "/"
+}
+
+// docURL returns the URL path of a doc of a version, as SUMMARY.md links to it
+func docURL(b *book.Book, version string, file string) string {
+	return (&url.URL{Path: "/" + b.Config.VersionPath(version) + "/" + file}).EscapedPath()
+}
+
+// render renders markdown as HTML
+func render(content []byte) []byte {
+	p := parser.NewWithExtensions(parser.CommonExtensions | parser.AutoHeadingIDs)
+	renderer := html.NewRenderer(html.RendererOptions{Flags: html.CommonFlags})
+	return markdown.ToHTML(content, p, renderer)
+}
@@ -0,0 +1,129 @@
+package serve
+
+import (
+	"bufio"
+	"context"
+	"github.com/brightercommand/Rewind/internal/book"
+	"github.com/brightercommand/Rewind/internal/sources"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeBook(t *testing.T) {
+	server := httptest.NewServer(newServer(t))
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	//the root of the book takes us to the first version
+	resp, err := client.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("Error getting page: %s", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/contents/8/Introduction.md" {
+		t.Errorf("Expected a redirect to /contents/8/Introduction.md, got %d to %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	//a doc is rendered, with the navigation of its version, and a switch to the other versions
+	body := get(t, server.URL+"/contents/10/Outbox.md", http.StatusOK)
+	for _, expected := range []string{
+		"<h1",
+		`<a href="/contents/10/Inbox.md">Inbox</a>`,
+		`<a href="/contents/10/Outbox.md" class="current">Outbox</a>`,
+		`<option value="/contents/9/Introduction.md">9</option>`,
+		`<option value="/contents/10/Introduction.md" selected>10</option>`,
+		"new EventSource(",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the page to contain %s, got\n%s", expected, body)
+		}
+	}
+
+	//SUMMARY.md is not a page of a version, but we can still read it
+	body = get(t, server.URL+"/SUMMARY.md", http.StatusOK)
+	if !strings.Contains(body, "Outbox") {
+		t.Errorf("Expected the summary, got\n%s", body)
+	}
+
+	get(t, server.URL+"/contents/10/Missing.md", http.StatusNotFound)
+}
+
+func TestServeReloadsPagesOnABuild(t *testing.T) {
+	s := newServer(t)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+EventsPath, nil)
+	if err != nil {
+		t.Fatalf("Error making request: %s", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error getting events: %s", err)
+	}
+	defer resp.Body.Close()
+
+	s.SetError(io.ErrUnexpectedEOF)
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "data: reload\n" {
+		t.Errorf("Expected a reload event, got %q, %v", line, err)
+	}
+
+	//we keep serving the last book, and show the error
+	body := get(t, server.URL+"/contents/10/Outbox.md", http.StatusOK)
+	if !strings.Contains(body, io.ErrUnexpectedEOF.Error()) {
+		t.Errorf("Expected the page to show the error, got\n%s", body)
+	}
+}
+
+func newServer(t *testing.T) *Server {
+	myDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Error getting working directory: %s", err)
+	}
+	sourcePath := strings.Replace(myDir, "internal/serve", "test/inheritance", 1)
+
+	src := sources.NewSources()
+	err = src.FindFromPath(sourcePath)
+	if err != nil {
+		t.Fatalf("Error finding sources: %s", err)
+	}
+
+	b, err := book.MakeBook(context.Background(), src, "", book.Options{})
+	if err != nil {
+		t.Fatalf("Error building book: %s", err)
+	}
+
+	s := New()
+	s.SetBook(b)
+	return s
+}
+
+func get(t *testing.T, url string, status int) string {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Error getting %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading %s: %s", url, err)
+	}
+	if resp.StatusCode != status {
+		t.Errorf("Expected %s to return %d, got %d", url, status, resp.StatusCode)
+	}
+	return string(body)
+}
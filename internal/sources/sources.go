@@ -1,29 +1,39 @@
 package sources
 
 import (
+	"context"
+	"github.com/brightercommand/Rewind/internal/config"
 	"github.com/brightercommand/Rewind/internal/pages"
+	"github.com/brightercommand/Rewind/internal/work"
+	"gopkg.in/yaml.v3"
 	"log"
 	"os"
 	"strings"
 )
 
-const gitBookFileName = ".gitbook.yaml"
-const tocFileName = ".toc.yaml"
-const sharedFolderName = "shared"
-const summaryFolderName = "summary"
+const readMeFileName = "README.md"
 const sharedVersion = "Shared"
 
+// workDirPrefix began the name of the work directory that older versions of Rewind made in the sources for SUMMARY.md
+const workDirPrefix = "summary"
+
+// Sources The sources for a book.
+// Config is the layout of the source folder; NewSources uses the default layout.
+// Jobs is how many folders we search at once; if it is less than one we use one per CPU.
 type Sources struct {
 	Root     *pages.Root
 	Shared   *pages.Shared
 	Versions map[string]pages.Version
+	Config   *config.Config
+	Jobs     int
 }
 
 func NewSources() *Sources {
 	return &Sources{
-		Root:     &pages.Root{},
+		Root:     &pages.Root{Files: make(map[string]pages.Doc)},
 		Shared:   &pages.Shared{},
 		Versions: make(map[string]pages.Version),
+		Config:   config.Default(),
 	}
 }
 
@@ -31,6 +41,8 @@ func NewSources() *Sources {
 // It takes a root directory as a string.
 // It returns a Sources struct.
 // The Sources struct contains the root directory, shared documents, and versioned documents.
+// We search the root folder, shared and each version at the same time, up to Jobs at once, and return the errors
+// from all of them.
 func (s *Sources) FindFromPath(root string) error {
 
 	entries, err := os.ReadDir(root)
@@ -40,33 +52,84 @@ func (s *Sources) FindFromPath(root string) error {
 
 	s.Root.SourcePath = root
 
+	var tasks []work.Task
+	var versions []*pages.Version
 	for _, entry := range entries {
-		if entry.Name() == gitBookFileName {
+		entry := entry
+		if entry.Name() == s.Config.GitBookFile {
 			s.Root.GitBook = &pages.Doc{
 				SourcePath: root,
 				Storage:    entry,
 			}
-		} else if entry.Name() == "README.md" {
+		} else if entry.Name() == readMeFileName {
 			s.Root.ReadMe = &pages.Doc{
 				SourcePath: root,
 				Storage:    entry,
 			}
-		} else if entry.IsDir() && entry.Name() == sharedFolderName {
-			shared, err := findShared(root, entry)
-			if err != nil {
-				return err
+		} else if entry.IsDir() && entry.Name() == s.Config.RootFolder {
+			tasks = append(tasks, func(ctx context.Context, logger *log.Logger) error {
+				logger.Print("Finding root files in " + root + "/" + entry.Name() + "...")
+				return findRootFiles(root+"/"+entry.Name(), "", s.Root.Files, logger)
+			})
+		} else if entry.IsDir() && entry.Name() == s.Config.SharedFolder {
+			tasks = append(tasks, func(ctx context.Context, logger *log.Logger) error {
+				logger.Print("Finding shared docs in " + root + "/" + entry.Name() + "...")
+				shared, err := s.findShared(root, entry)
+				if err != nil {
+					return err
+				}
+				s.Shared = shared
+				return nil
+			})
+		} else if entry.IsDir() && !s.isSummaryFolder(entry.Name()) {
+			if !s.Config.IsVersionFolder(entry.Name()) {
+				log.Print("Skipping " + root + "/" + entry.Name() + " as it does not match the version pattern...")
+				continue
 			}
-			s.Shared = shared
-		} else if entry.IsDir() && entry.Name() != summaryFolderName {
-			version, err := findVersion(root, entry)
-			if err != nil {
-				return err
-			}
-			s.Versions[entry.Name()] = *version
+			i := len(versions)
+			versions = append(versions, nil)
+			tasks = append(tasks, func(ctx context.Context, logger *log.Logger) error {
+				logger.Print("Finding versioned docs in " + root + "/" + entry.Name() + "...")
+				version, err := s.findVersion(root, entry)
+				if err != nil {
+					return err
+				}
+				versions[i] = version
+				return nil
+			})
+		}
+	}
+
+	err = work.Join(work.Run(context.Background(), s.Jobs, tasks))
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		s.Versions[version.Version] = *version
+	}
+	return nil
+}
+
+// isSummaryFolder whether a folder is the summary folder, or a work directory left behind in the sources by an older
+// version of Rewind, named summary followed by digits; neither is a version.
+func (s *Sources) isSummaryFolder(name string) bool {
+	if name == s.Config.SummaryFolder {
+		return true
+	}
+
+	digits := strings.TrimPrefix(name, workDirPrefix)
+	if digits == name || digits == "" {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
 		}
 	}
 
-	return err
+	log.Print("Skipping " + name + " as it was left behind by an earlier run...")
+	return true
 }
 
 // findVersionedDocs finds the versioned documents for the book.
@@ -74,7 +137,7 @@ func (s *Sources) FindFromPath(root string) error {
 // It returns an error.
 // We assume that all documents are in the root of the version folder.
 // We assume that sub-folders are used for images.
-func findVersionedDocs(path string, version *pages.Version) (err error) {
+func (s *Sources) findVersionedDocs(path string, version *pages.Version) (err error) {
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
@@ -83,13 +146,30 @@ func findVersionedDocs(path string, version *pages.Version) (err error) {
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
-			if entry.Name() == tocFileName {
+			if entry.Name() == s.Config.TocFile {
 				version.TOC = &pages.Doc{SourcePath: path, Version: version.Version, Storage: entry}
+				version.Base, version.Exclude = readSettings(path + "/" + entry.Name())
+			} else if entry.Name() == s.Config.VarsFile {
+				version.VarsFile = &pages.Doc{SourcePath: path, Version: version.Version, Storage: entry}
 			} else if isMarkDownFile(entry) {
 				version.Docs[entry.Name()] = pages.Doc{SourcePath: path, Version: version.Version, Storage: entry}
+				if entry.Name() == readMeFileName {
+					readMe := version.Docs[entry.Name()]
+					version.ReadMe = &readMe
+				}
+			}
+		} else if entry.Name() == s.Config.StaticFolder {
+			err = s.findStatic(path+"/"+entry.Name(), version.Version, version.Images)
+			if err != nil {
+				return err
 			}
-		} else if entry.Name() == pages.StaticFolderName {
-			err = findVersionedImages(path+"/"+entry.Name(), version)
+		} else if entry.Name() == s.Config.PartialsFolder {
+			err = s.findPartials(path+"/"+entry.Name(), "", version.Version, version.Partials)
+			if err != nil {
+				return err
+			}
+		} else if entry.Name() == s.Config.SamplesFolder {
+			err = s.findSamples(path+"/"+entry.Name(), "", version.Version, version.Samples)
 			if err != nil {
 				return err
 			}
@@ -99,7 +179,12 @@ func findVersionedDocs(path string, version *pages.Version) (err error) {
 	return nil
 }
 
-func findVersionedImages(path string, version *pages.Version) error {
+// findSharedDocs finds the documents for the book.
+// It takes a directory entry and a Shared struct.
+// It returns an error.
+// We assume that the documents are the root level
+// We assume that sub-folders are used for images.
+func (s *Sources) findSharedDocs(path string, shared *pages.Shared) (err error) {
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
@@ -108,11 +193,25 @@ func findVersionedImages(path string, version *pages.Version) error {
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
-			if isImageFile(entry.Name()) {
-				version.Images[entry.Name()] = pages.Asset{SourcePath: path, What: pages.Image, Version: version.Version, Storage: entry}
+			if entry.Name() == s.Config.TocFile {
+				shared.TOC = &pages.Doc{SourcePath: path, Version: sharedVersion, Storage: entry}
+			} else if entry.Name() == s.Config.VarsFile {
+				shared.VarsFile = &pages.Doc{SourcePath: path, Version: sharedVersion, Storage: entry}
+			} else if isMarkDownFile(entry) {
+				shared.Docs[entry.Name()] = pages.Doc{SourcePath: path, Version: sharedVersion, Storage: entry}
+			}
+		} else if entry.Name() == s.Config.StaticFolder {
+			err = s.findStatic(path+"/"+entry.Name(), sharedVersion, shared.Images)
+			if err != nil {
+				return err
 			}
-		} else if entry.Name() == pages.ImageFolderName {
-			err = findVersionedImages(path+"/"+entry.Name(), version)
+		} else if entry.Name() == s.Config.PartialsFolder {
+			err = s.findPartials(path+"/"+entry.Name(), "", sharedVersion, shared.Partials)
+			if err != nil {
+				return err
+			}
+		} else if entry.Name() == s.Config.SamplesFolder {
+			err = s.findSamples(path+"/"+entry.Name(), "", sharedVersion, shared.Samples)
 			if err != nil {
 				return err
 			}
@@ -120,30 +219,93 @@ func findVersionedImages(path string, version *pages.Version) error {
 	}
 
 	return nil
-
 }
 
-// findSharedDocs finds the documents for the book.
-// It takes a directory entry and a Shared struct.
+// findRootFiles finds the files that are copied as-is to the root of the book.
+// It takes the path of the folder, the path of that folder relative to the root folder, and the map to add files to.
 // It returns an error.
-// We assume that the documents are the root level
-// We assume that sub-folders are used for images.
-func findSharedDocs(path string, shared *pages.Shared) (err error) {
+// Files are keyed by their path relative to the root folder. We never copy a SUMMARY.md as we generate that file.
+func findRootFiles(path string, relativePath string, files map[string]pages.Doc, logger *log.Logger) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
 
+	for _, entry := range entries {
+		key := entry.Name()
+		if relativePath != "" {
+			key = relativePath + "/" + entry.Name()
+		}
+
+		if !entry.IsDir() {
+			if key == pages.SummaryFileName {
+				logger.Print("Ignoring " + path + "/" + entry.Name() + " as " + pages.SummaryFileName + " is generated...")
+				continue
+			}
+			files[key] = pages.Doc{SourcePath: path, Storage: entry}
+		} else {
+			err = findRootFiles(path+"/"+entry.Name(), key, files, logger)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findImages finds the images under an images folder.
+// It takes the path of the folder, the path of that folder relative to the images folder, and the version that owns
+// the images. It returns an error.
+// Images are keyed by their path relative to the images folder, so that nested folders keep their layout when
+// published, and two images with the same name in different folders do not overwrite each other.
+func (s *Sources) findImages(path string, relativePath string, version string, images map[string]pages.Asset) error {
 	entries, err := os.ReadDir(path)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
+		key := entry.Name()
+		if relativePath != "" {
+			key = relativePath + "/" + entry.Name()
+		}
+
 		if !entry.IsDir() {
-			if entry.Name() == tocFileName {
-				shared.TOC = &pages.Doc{SourcePath: path, Version: sharedVersion, Storage: entry}
-			} else if isMarkDownFile(entry) {
-				shared.Docs[entry.Name()] = pages.Doc{SourcePath: path, Version: sharedVersion, Storage: entry}
+			if s.Config.IsImageFile(entry.Name()) {
+				images[key] = pages.Asset{SourcePath: path, What: pages.Image, Version: version, Storage: entry}
+			}
+		} else {
+			err = s.findImages(path+"/"+entry.Name(), key, version, images)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findPartials finds the partials in a partials folder, and any folders within it.
+// Each partial is keyed by its path relative to the partials folder.
+func (s *Sources) findPartials(path string, relativePath string, version string, partials map[string]pages.Doc) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		key := entry.Name()
+		if relativePath != "" {
+			key = relativePath + "/" + entry.Name()
+		}
+
+		if !entry.IsDir() {
+			if isMarkDownFile(entry) {
+				partials[key] = pages.Doc{SourcePath: path, Version: version, Storage: entry}
 			}
-		} else if entry.Name() == pages.StaticFolderName {
-			err = findSharedImages(path+"/"+entry.Name(), shared)
+		} else {
+			err = s.findPartials(path+"/"+entry.Name(), key, version, partials)
 			if err != nil {
 				return err
 			}
@@ -153,19 +315,46 @@ func findSharedDocs(path string, shared *pages.Shared) (err error) {
 	return nil
 }
 
-func findSharedImages(path string, shared *pages.Shared) error {
+// findSamples finds the source files in a samples folder, and any folders within it.
+// Each sample is keyed by its path relative to the samples folder.
+func (s *Sources) findSamples(path string, relativePath string, version string, samples map[string]pages.Asset) error {
 	entries, err := os.ReadDir(path)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
+		key := entry.Name()
+		if relativePath != "" {
+			key = relativePath + "/" + entry.Name()
+		}
+
 		if !entry.IsDir() {
-			if isImageFile(entry.Name()) {
-				shared.Images[entry.Name()] = pages.Asset{SourcePath: path, What: pages.Image, Version: sharedVersion, Storage: entry}
+			samples[key] = pages.Asset{SourcePath: path, What: pages.Sample, Version: version, Storage: entry}
+		} else {
+			err = s.findSamples(path+"/"+entry.Name(), key, version, samples)
+			if err != nil {
+				return err
 			}
-		} else if entry.Name() == pages.ImageFolderName {
-			err = findSharedImages(path+"/"+entry.Name(), shared)
+		}
+	}
+
+	return nil
+}
+
+// findStatic finds the assets in a _static folder.
+// It takes the path of the _static folder and the version that owns the assets.
+// It returns an error.
+// We only publish the images folder from _static.
+func (s *Sources) findStatic(path string, version string, images map[string]pages.Asset) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() == s.Config.ImageFolder {
+			err = s.findImages(path+"/"+entry.Name(), "", version, images)
 			if err != nil {
 				return err
 			}
@@ -178,16 +367,18 @@ func findSharedImages(path string, shared *pages.Shared) error {
 // findShared finds the shared documents for the book.
 // It takes a directory entry and its path.
 // It returns a Shared struct.
-func findShared(path string, entry os.DirEntry) (shared *pages.Shared, err error) {
+func (s *Sources) findShared(path string, entry os.DirEntry) (shared *pages.Shared, err error) {
 	shared = &pages.Shared{
-		Docs:   make(map[string]pages.Doc),
-		Images: make(map[string]pages.Asset),
+		Docs:     make(map[string]pages.Doc),
+		Images:   make(map[string]pages.Asset),
+		Partials: make(map[string]pages.Doc),
+		Samples:  make(map[string]pages.Asset),
 	}
 
 	sharedPath := path + "/" + entry.Name()
+	shared.SourcePath = sharedPath
 
-	log.Print("Finding shared docs in " + sharedPath + "...")
-	err = findSharedDocs(sharedPath, shared)
+	err = s.findSharedDocs(sharedPath, shared)
 	if err != nil {
 		return shared, err
 	}
@@ -199,18 +390,20 @@ func findShared(path string, entry os.DirEntry) (shared *pages.Shared, err error
 // findVersion finds the versioned documents for the book.
 // It takes a directory entry and its path.
 // It returns a Version struct.
-func findVersion(path string, entry os.DirEntry) (version *pages.Version, err error) {
+func (s *Sources) findVersion(path string, entry os.DirEntry) (version *pages.Version, err error) {
 
 	version = &pages.Version{
-		Docs:    make(map[string]pages.Doc),
-		Images:  make(map[string]pages.Asset),
-		Version: entry.Name(),
+		Docs:     make(map[string]pages.Doc),
+		Images:   make(map[string]pages.Asset),
+		Partials: make(map[string]pages.Doc),
+		Samples:  make(map[string]pages.Asset),
+		Version:  entry.Name(),
 	}
 
 	versionPath := path + "/" + entry.Name()
-	log.Print("Finding versioned docs in " + versionPath + "...")
+	version.SourcePath = versionPath
 
-	err = findVersionedDocs(versionPath, version)
+	err = s.findVersionedDocs(versionPath, version)
 	if err != nil {
 		return version, err
 	}
@@ -219,18 +412,21 @@ func findVersion(path string, entry os.DirEntry) (version *pages.Version, err er
 
 }
 
-// Helper function to check if a file has an image extension
-func isImageFile(filename string) bool {
-	// Define a list of image file extensions
-	imageExtensions := []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg"}
+// readSettings reads the base version and the excluded files, if any, from a version's .toc.yaml
+// We don't fail if the file cannot be read or parsed here; the book reports that when it loads the TOC.
+func readSettings(tocPath string) (base string, exclude []string) {
+	file, err := os.ReadFile(tocPath)
+	if err != nil {
+		return "", nil
+	}
 
-	// Check if the filename ends with any of the image extensions
-	for _, ext := range imageExtensions {
-		if strings.HasSuffix(filename, ext) {
-			return true
-		}
+	var toc pages.Toc
+	err = yaml.Unmarshal(file, &toc)
+	if err != nil {
+		return "", nil
 	}
-	return false
+
+	return toc.Base, toc.Exclude
 }
 
 func isMarkDownFile(entry os.DirEntry) bool {
@@ -38,6 +38,11 @@ func checkRoot(t *testing.T, sources *Sources) {
 	if root.ReadMe.Storage.Name() != "README.md" {
 		t.Errorf("Expected README.md")
 	}
+
+	contributing, ok := root.Files["CONTRIBUTING.md"]
+	if !ok || contributing.Storage.Name() != "CONTRIBUTING.md" {
+		t.Errorf("Expected CONTRIBUTING.md in the root files")
+	}
 }
 
 func checkShared(t *testing.T, sources *Sources) {
@@ -72,8 +77,8 @@ func checkShared(t *testing.T, sources *Sources) {
 
 	images := sources.Shared.Images
 
-	if len(images) != 2 {
-		t.Errorf("Expected 1 image, got %d", len(images))
+	if len(images) != 3 {
+		t.Errorf("Expected 3 images, got %d", len(images))
 	} else {
 		if images["ImageOne.png"].Storage.Name() != "ImageOne.png" {
 			t.Errorf("Expected ImageOne.png, got %s", images["ImageOne.png"].Storage.Name())
@@ -82,6 +87,13 @@ func checkShared(t *testing.T, sources *Sources) {
 		if images["ImageTwo.png"].Storage.Name() != "ImageTwo.png" {
 			t.Errorf("Expected ImageTwo.png, got %s", images["ImageTwo.png"].Storage.Name())
 		}
+
+		nested, ok := images["diagrams/ImageFive.png"]
+		if !ok || nested.Storage.Name() != "ImageFive.png" {
+			t.Errorf("Expected diagrams/ImageFive.png to keep its relative path")
+		} else if !strings.HasSuffix(nested.SourcePath, "_static/images/diagrams") {
+			t.Errorf("Expected diagrams/ImageFive.png to be found in _static/images/diagrams, got %s", nested.SourcePath)
+		}
 	}
 
 	return
@@ -101,9 +113,13 @@ func checkVersions(t *testing.T, sources *Sources) {
 				t.Errorf("Expected .toc.yaml, got %s", version.TOC)
 			}
 
+			if version.ReadMe == nil || version.ReadMe.Storage.Name() != "README.md" {
+				t.Errorf("Expected README.md as the landing page for 10")
+			}
+
 			docs := version.Docs
-			if len(docs) != 2 {
-				t.Errorf("Expected 2 documents, got %d", len(docs))
+			if len(docs) != 3 {
+				t.Errorf("Expected 3 documents, got %d", len(docs))
 			} else {
 
 				if docs["DocumentOne.md"].Storage.Name() != "DocumentOne.md" {
@@ -138,6 +154,10 @@ func checkVersions(t *testing.T, sources *Sources) {
 				t.Errorf("Expected .toc.yaml, got %s", version.TOC)
 			}
 
+			if version.ReadMe != nil {
+				t.Errorf("Expected no landing page for 9, got %s", version.ReadMe.Storage.Name())
+			}
+
 			docs := version.Docs
 			if len(docs) != 1 {
 				t.Errorf("Expected 1 document, got %d", len(docs))
@@ -163,3 +183,36 @@ func checkVersions(t *testing.T, sources *Sources) {
 		}
 	}
 }
+
+func TestSkipLeftoverWorkDirectories(t *testing.T) {
+	sourcePath := t.TempDir()
+	for _, folder := range []string{"shared", "9", "summary", "summary1234567", "summary-notes"} {
+		err := os.Mkdir(sourcePath+"/"+folder, os.ModePerm)
+		if err != nil {
+			t.Fatalf("Error making folder: %s", err)
+		}
+	}
+
+	err := os.WriteFile(sourcePath+"/summary1234567/SUMMARY.md", []byte("## 9\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+
+	sources := NewSources()
+	err = sources.FindFromPath(sourcePath)
+	if err != nil {
+		t.Errorf("Error finding sources: %s", err)
+	}
+
+	if _, ok := sources.Versions["summary1234567"]; ok {
+		t.Errorf("Expected a leftover work directory not to be a version")
+	}
+
+	if _, ok := sources.Versions["summary-notes"]; !ok {
+		t.Errorf("Expected summary-notes to be a version, as it is not a leftover work directory")
+	}
+
+	if len(sources.Versions) != 2 {
+		t.Errorf("Expected 2 versions, got %d", len(sources.Versions))
+	}
+}
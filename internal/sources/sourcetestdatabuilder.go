@@ -46,6 +46,15 @@ func SourceTestDataBuilder(sourcePath string, mydir string) *Sources {
 					isDir: false,
 				},
 			},
+			Files: map[string]pages.Doc{
+				"CONTRIBUTING.md": {
+					SourcePath: sourcePath + "/root",
+					Storage: fakeDirEntry{
+						name:  "CONTRIBUTING.md",
+						isDir: false,
+					},
+				},
+			},
 			SourcePath: sourcePath,
 		},
 		Shared: &pages.Shared{
@@ -183,6 +192,17 @@ func SourceTestDataBuilder(sourcePath string, mydir string) *Sources {
 			isDir: false,
 		}}
 
+	versionTen.Docs["README.md"] = pages.Doc{
+		SourcePath: strings.Replace(mydir, "internal/book", "test/source/10", 1),
+		Version:    "10",
+		Storage: fakeDirEntry{
+			name:  "README.md",
+			isDir: false,
+		}}
+
+	readMe := versionTen.Docs["README.md"]
+	versionTen.ReadMe = &readMe
+
 	//add shared images
 	versionTen.Images["ImageOne.png"] = pages.Asset{
 		SourcePath: strings.Replace(mydir, "interna/bookl", "test/source/shared/_static/images", 1),
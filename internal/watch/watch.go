@@ -0,0 +1,135 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileState What we know about a file when we look at it, enough to tell if it has changed since
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher Polls a folder for changes to the files in it.
+// We poll, rather than ask the operating system to tell us about changes, so that we work the same way everywhere.
+type Watcher struct {
+	root     string
+	interval time.Duration
+	debounce time.Duration
+	files    map[string]fileState
+}
+
+// New returns a watcher that looks at the files in root every interval, and waits for debounce without a change before
+// it reports a burst of changes.
+// It looks at the files straight away, so that it reports only the changes after it was made.
+func New(root string, interval time.Duration, debounce time.Duration) (*Watcher, error) {
+	files, err := snapshot(root)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{root: root, interval: interval, debounce: debounce, files: files}, nil
+}
+
+// Next waits until a file in the folder is added, changed or removed, then until there has been no further change for
+// the debounce period, and returns every file that changed, relative to the folder, using / as a separator, in order.
+// It stops, and returns the context's error, if the context is cancelled.
+func (w *Watcher) Next(ctx context.Context) ([]string, error) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	changed := make(map[string]bool)
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		files, err := snapshot(w.root)
+		if err != nil {
+			return nil, err
+		}
+
+		paths := diff(w.files, files)
+		w.files = files
+		for _, path := range paths {
+			changed[path] = true
+		}
+
+		now := time.Now()
+		if len(paths) > 0 {
+			lastChange = now
+			continue
+		}
+
+		if len(changed) > 0 && now.Sub(lastChange) >= w.debounce {
+			return sortedPaths(changed), nil
+		}
+	}
+}
+
+// snapshot returns the size and modification time of every file in a folder, by its path relative to the folder.
+// We skip hidden folders, such as .git, as they are not part of the sources.
+func snapshot(root string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// diff returns the files that were added, changed or removed between two snapshots, in order
+func diff(old map[string]fileState, new map[string]fileState) []string {
+	changed := make(map[string]bool)
+	for path, state := range new {
+		if previous, ok := old[path]; !ok || previous.size != state.size || !previous.modTime.Equal(state.modTime) {
+			changed[path] = true
+		}
+	}
+	for path := range old {
+		if _, ok := new[path]; !ok {
+			changed[path] = true
+		}
+	}
+	return sortedPaths(changed)
+}
+
+// sortedPaths returns the paths in a set, in order
+func sortedPaths(set map[string]bool) []string {
+	paths := make([]string, 0, len(set))
+	for path := range set {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
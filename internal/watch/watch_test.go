@@ -0,0 +1,72 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNextReportsABurstOfChangesOnce(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "shared", "Doc.md"), "doc")
+	writeFile(t, filepath.Join(root, "9", "Gone.md"), "gone")
+
+	watcher, err := New(root, 5*time.Millisecond, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Error creating watcher: %s", err)
+	}
+
+	//a burst of edits, spread over more than one poll
+	go func() {
+		writeFile(t, filepath.Join(root, "shared", "Doc.md"), "changed doc")
+		time.Sleep(15 * time.Millisecond)
+		writeFile(t, filepath.Join(root, "9", "New.md"), "new")
+		time.Sleep(15 * time.Millisecond)
+		_ = os.Remove(filepath.Join(root, "9", "Gone.md"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changed, err := watcher.Next(ctx)
+	if err != nil {
+		t.Fatalf("Error waiting for changes: %s", err)
+	}
+
+	expected := []string{"9/Gone.md", "9/New.md", "shared/Doc.md"}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("Expected changes %v, got %v", expected, changed)
+	}
+}
+
+func TestNextIgnoresHiddenFolders(t *testing.T) {
+	root := t.TempDir()
+
+	watcher, err := New(root, 5*time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Error creating watcher: %s", err)
+	}
+
+	writeFile(t, filepath.Join(root, ".git", "index"), "index")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	changed, err := watcher.Next(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected no changes, got %v and error %v", changed, err)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err == nil {
+		err = os.WriteFile(path, []byte(content), 0644)
+	}
+	if err != nil {
+		t.Errorf("Error writing %s: %s", path, err)
+	}
+}
@@ -0,0 +1,149 @@
+package work
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Task A unit of work.
+// It should log to the logger it is given, rather than the standard logger, so that its log is written in order with
+// the logs of the other tasks.
+type Task func(ctx context.Context, logger *log.Logger) error
+
+// Jobs returns the number of workers to use: jobs, or one per CPU if jobs is less than one
+func Jobs(jobs int) int {
+	if jobs < 1 {
+		return runtime.NumCPU()
+	}
+	return jobs
+}
+
+// Run runs the tasks on up to jobs workers at once, see Jobs, and returns the error of each task, in the order of the
+// tasks; the error of a task that succeeded is nil.
+// Each task logs to a buffer, which we write to the standard logger once that task, and every task before it, has
+// finished, so the log is in the same order however the work is scheduled.
+// Once the context is cancelled we start no more tasks; the error of a task that we did not start is the context's.
+func Run(ctx context.Context, jobs int, tasks []Task) []error {
+	errs := make([]error, len(tasks))
+	logs := newOrderedLog(len(tasks))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < Jobs(jobs) && w < len(tasks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				logger := log.New(logs.buffer(i), log.Prefix(), log.Flags())
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+				} else {
+					errs[i] = tasks[i](ctx, logger)
+				}
+				logs.done(i)
+			}
+		}()
+	}
+
+	for i := range tasks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return errs
+}
+
+// orderedLog The logs of a set of tasks, which we write in the order of the tasks as they finish
+type orderedLog struct {
+	mu       sync.Mutex
+	buffers  []*bytes.Buffer
+	finished []bool
+	next     int
+}
+
+func newOrderedLog(n int) *orderedLog {
+	buffers := make([]*bytes.Buffer, n)
+	for i := range buffers {
+		buffers[i] = &bytes.Buffer{}
+	}
+	return &orderedLog{buffers: buffers, finished: make([]bool, n)}
+}
+
+// buffer returns the buffer that a task logs to
+func (l *orderedLog) buffer(i int) *bytes.Buffer {
+	return l.buffers[i]
+}
+
+// done marks a task as finished, and writes the log of every finished task that no unfinished task comes before
+func (l *orderedLog) done(i int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.finished[i] = true
+	for l.next < len(l.finished) && l.finished[l.next] {
+		_, _ = log.Writer().Write(l.buffers[l.next].Bytes())
+		l.buffers[l.next] = nil
+		l.next++
+	}
+}
+
+// Errors The errors of every task that failed, or of every part of a build, in the order they happened, so that one
+// run reports every failure
+type Errors []error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap lets errors.Is and errors.As find any of the errors
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Add adds an error, unless it is nil or we already have an error with the same message. If the error is itself
+// Errors, we add each of its errors, so that Errors is never nested.
+func (e *Errors) Add(err error) {
+	if err == nil {
+		return
+	}
+
+	if errs, ok := err.(Errors); ok {
+		for _, err := range errs {
+			e.Add(err)
+		}
+		return
+	}
+
+	for _, existing := range *e {
+		if existing.Error() == err.Error() {
+			return
+		}
+	}
+	*e = append(*e, err)
+}
+
+// OrNil returns nil if there are no errors, so that an empty Errors is not mistaken for an error
+func (e Errors) OrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Join returns the errors that are not nil, as Errors, or nil if they are all nil
+func Join(errs []error) error {
+	var joined Errors
+	for _, err := range errs {
+		joined.Add(err)
+	}
+	return joined.OrNil()
+}
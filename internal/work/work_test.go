@@ -0,0 +1,99 @@
+package work
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestRunLogsInOrder(t *testing.T) {
+	var out bytes.Buffer
+	writer, flags := log.Writer(), log.Flags()
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	}()
+
+	//later tasks finish first
+	var tasks []Task
+	for i := 0; i < 8; i++ {
+		i := i
+		tasks = append(tasks, func(ctx context.Context, logger *log.Logger) error {
+			time.Sleep(time.Duration(8-i) * time.Millisecond)
+			logger.Printf("task %d starts", i)
+			logger.Printf("task %d ends", i)
+			if i%3 == 0 {
+				return fmt.Errorf("task %d failed", i)
+			}
+			return nil
+		})
+	}
+
+	errs := Run(context.Background(), 4, tasks)
+
+	var expected bytes.Buffer
+	for i := 0; i < 8; i++ {
+		fmt.Fprintf(&expected, "task %d starts\ntask %d ends\n", i, i)
+	}
+	if out.String() != expected.String() {
+		t.Errorf("Expected the logs in the order of the tasks:\n%s\ngot:\n%s", expected.String(), out.String())
+	}
+
+	err := Join(errs)
+	if err == nil || err.Error() != "task 0 failed\ntask 3 failed\ntask 6 failed" {
+		t.Errorf("Expected the errors of every failed task, in order, got %v", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := make([]bool, 4)
+	var tasks []Task
+	for i := range ran {
+		i := i
+		tasks = append(tasks, func(ctx context.Context, logger *log.Logger) error {
+			ran[i] = true
+			cancel()
+			return nil
+		})
+	}
+
+	errs := Run(ctx, 1, tasks)
+
+	if !ran[0] || ran[1] || ran[2] || ran[3] {
+		t.Errorf("Expected only the first task to run, got %v", ran)
+	}
+	for _, err := range errs[1:] {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the tasks that did not run to be cancelled, got %v", err)
+		}
+	}
+}
+
+func TestErrorsAdd(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+
+	var errs Errors
+	errs.Add(nil)
+	if errs.OrNil() != nil {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	//we flatten Errors, and drop an error with the same message as one we have
+	errs.Add(first)
+	errs.Add(Errors{errors.New("first"), second})
+	if errs.Error() != "first\nsecond" {
+		t.Errorf("Expected first and second, got %q", errs.Error())
+	}
+	if !errors.Is(errs.OrNil(), second) {
+		t.Errorf("Expected to find the second error")
+	}
+}
@@ -0,0 +1,8 @@
+using Paramore.Brighter;
+
+await host.RunAsync(cancellationToken);
+
+// #region ConfigureOutbox
+services.AddBrighter()
+    .AddProducers(configure => configure.Outbox = new InMemoryOutbox());
+// #endregion
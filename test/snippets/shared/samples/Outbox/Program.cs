@@ -0,0 +1,17 @@
+using Paramore.Brighter;
+
+await host.RunAsync();
+public class Program
+{
+    public static void Configure(IServiceCollection services)
+    {
+        #region ConfigureOutbox
+        services.AddBrighter()
+            #region UseOutbox
+            .UseOutbox(new InMemoryOutbox())
+            #endregion
+            .Log($"{{Outbox}}");
+
+        #endregion
+    }
+}